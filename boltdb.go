@@ -5,7 +5,7 @@ import (
 	"os"
 
 	"github.com/AndrewDonelson/golog"
-	"github.com/boltdb/bolt"
+	bolt "go.etcd.io/bbolt"
 )
 
 // BoltDB global access to BoltDB resource
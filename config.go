@@ -1,7 +1,9 @@
 package goutils
 
 import (
-	"encoding/json"
+	"encoding"
+	"errors"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
@@ -18,17 +20,21 @@ const (
 	MaxIterations = 100
 )
 
-// Parsed contains each JSON element, remembering where it was found
+// Parsed contains each config element, remembering where it was found
 // - DistinctName is shortest unique name across all filenames
 // - Position is element # within the file: 0 if single element, 1...N if array of N elements
+// - Override sources, such as command-line flags, take precedence whatever the MergePolicy
+// - KeyPositions is the line and column of each element key, nil for sources other than files
 type Parsed struct {
 	FileName     string
 	DistinctName string
 	Position     int
 	ElementMap   ElementMap
+	Override     bool
+	KeyPositions KeyPositions
 }
 
-// ElementMap is the JSON element parsed into a key-value map
+// ElementMap is the config element parsed into a key-value map
 type ElementMap map[string]interface{}
 
 // ParsedMap When mutiple files are parsed, a field in each element is specified as the Id
@@ -38,59 +44,216 @@ type ParsedMap map[string][]Parsed
 // ResultMap The Parsed map form is then collapsed into a single data object result per Id
 type ResultMap map[string]interface{}
 
+// ProvenanceMap Source of each parameter's final value, per element Id then parameter path
+// - Source is the file's DistinctName, with :elem#N if from an array, or env:NAME, flag:NAME or default
+// - Slices and maps appended from several sources list each, comma separated
+type ProvenanceMap map[string]map[string]string
+
+// MergePolicy How values given for the same element parameter by more than one source are resolved
+type MergePolicy int
+
+const (
+	// MergeError Different values are reported as a conflict error
+	MergeError MergePolicy = iota
+	// MergeLastWins The value from the last file, in filename order, is used
+	MergeLastWins
+	// MergeFirstWins The value from the first file, in filename order, is used
+	MergeFirstWins
+)
+
+// MergeMode How slices or maps given by more than one source are combined
+type MergeMode int
+
+const (
+	// MergeReplace The winning source's slice or map is used, as for any other value
+	MergeReplace MergeMode = iota
+	// MergeAppend Slices are appended, maps merged key by key, in source order
+	MergeAppend
+)
+
+// UnknownKeyPolicy How element keys that don't match any data object field are reported
+type UnknownKeyPolicy int
+
+const (
+	// UnknownKeysDefault Errors for ReadConfigFiles, ignored by ReadConfigFile
+	UnknownKeysDefault UnknownKeyPolicy = iota
+	// UnknownKeysStrict Unknown keys are errors
+	UnknownKeysStrict
+	// UnknownKeysLenient Unknown keys are logged as warnings
+	UnknownKeysLenient
+	// UnknownKeysIgnore Unknown keys are not reported
+	UnknownKeysIgnore
+)
+
+// ConfigLoader Options for reading config files
+// - The zero value reads files only, as ReadConfigFile and ReadConfigFiles do
+type ConfigLoader struct {
+	// Env Overlay fields tagged `env:"NAME"` with the value of environment variable NAME
+	Env bool
+
+	// EnvPrefix Overlay untagged fields from EnvPrefix + json tag name in upper case, e.g. APP_TIMEOUT
+	// - Setting EnvPrefix also enables Env
+	EnvPrefix string
+
+	// Flags Apply flags set in this FlagSet, made by NewConfigFlagSet, over files and environment
+	Flags *flag.FlagSet
+
+	// Merge How values from layered files are resolved, the default MergeError reports conflicts
+	// - Environment variables follow files, command-line flags always take precedence
+	Merge MergePolicy
+
+	// MergeSlices, MergeMaps How slices and maps from several sources combine, unless Merge is MergeError
+	MergeSlices MergeMode
+	MergeMaps   MergeMode
+
+	// UnknownKeys How keys not matching any field are reported, with the closest field name as a suggestion
+	UnknownKeys UnknownKeyPolicy
+
+	// Keys Supplies the key to decrypt enc:v1: values, see EncryptConfigValue
+	Keys KeyProvider
+
+	// Location Zone of dates and times given without one, UTC if nil
+	Location *time.Location
+
+	// CaseInsensitiveKeys Match element keys to json tag and field names ignoring case, e.g. Port or PORT for port
+	// - A key matching exactly is always preferred
+	CaseInsensitiveKeys bool
+}
+
 // ReadConfigFile Read a single config file, return a struct, where 'data' is a pointer to that struct
+// - File format is chosen by extension, see ConfigFormat
+// - Files it includes are read first, so the file overrides them, see ConfigIncludeKey
+// - Errors with ErrInvalidTarget if 'data' is not a pointer to a struct, and a *ConfigElementError if the file is not an element
 func ReadConfigFile(data interface{}, filename string) (err error) {
-	var b []byte
+	return (&ConfigLoader{}).ReadConfigFile(data, filename)
+}
+
+// ReadConfigFile Read a single config file using loader options, see ReadConfigFile
+func (loader *ConfigLoader) ReadConfigFile(data interface{}, filename string) (err error) {
 	var errList ErrList
-	var config interface{}
-	var k string
+	var b []byte
 
 	// Make sure data is a pointer to a struct
-	k = reflect.TypeOf(data).Kind().String()
-	if k != "ptr" {
-		err = fmt.Errorf("ReadConfigFile: 'data' must be ptr, not %s", k)
-		panic(err)
+	st, sv, err := configTarget("ReadConfigFile", data)
+	if err != nil {
+		return
 	}
-	st := reflect.TypeOf(data).Elem()
-	sv := reflect.ValueOf(data).Elem()
 
 	_, _, err = ValidateFile(filename)
 	if err != nil {
 		return
 	}
 
+	// A file with includes is read as a layer over the files it includes
+	inc := newIncluder(&errList)
+	inc.include(filename, nil)
+	decoded, ok := inc.decoded[filename]
+	if decoded.includes || len(inc.expanded) > 1 || len(errList) > 0 {
+		return loader.readIncludingFile(st, sv, inc)
+	}
+	if ok {
+		return loader.readConfigElement(st, sv, filename, filepath.Base(filename), decoded.config, decoded.positions)
+	}
+
+	// The includer couldn't read or decode the file, read it again to report why
 	if b, err = ioutil.ReadFile(filename); err != nil {
 		err = fmt.Errorf("reading file: %v", err)
 		return
 	}
+	return loader.readConfigBytes(st, sv, filename, filepath.Base(filename), b)
+}
+
+// readIncludingFile Read the config file inc has expanded, and the files it includes, as a single element into data object (st, sv)
+// - Included files go first, so the file overrides them, see ConfigIncludeKey
+// - A file holding nothing but includes adds no values of its own
+func (loader *ConfigLoader) readIncludingFile(st reflect.Type, sv reflect.Value, inc *includer) (err error) {
+	var typedErrs []error
+	var parsedArr []Parsed
+	var config interface{}
+	var positions KeyPositions
+
+	errList := inc.errList
+	for _, source := range inc.load(FileSources(inc.expanded...)) {
+		config, positions = loader.tomlElements(source.file.Name, source.config, source.positions, st)
+		if elements, isArr := config.([]interface{}); isArr && len(elements) == 0 && inc.decoded[source.file.Name].includes {
+			continue
+		}
+
+		// Each file can contain a single element of type 'data'
+		element, isMap := config.(map[string]interface{})
+		if !isMap {
+			elementErr := &ConfigElementError{File: source.file.Name, Type: valueKind(config), Err: ErrElementNotObject}
+			errList.Addf("%v, skipping", elementErr)
+			typedErrs = append(typedErrs, elementErr)
+			continue
+		}
+		golog.Log.Debugf("Parsing single element [%s]", source.file.Name)
+		parsedArr = append(parsedArr, Parsed{
+			FileName:     source.file.Name,
+			DistinctName: source.file.DistinctName,
+			ElementMap:   element,
+			KeyPositions: positions,
+		})
+	}
+	if err = withErrors(errList.Get(), typedErrs); err != nil {
+		return
+	}
+	return loader.readElement(st, sv, parsedArr)
+}
 
-	// Parse config file into map[string]interface{}
-	err = json.Unmarshal(b, &config)
+// readConfigBytes Read config b, named name, as a single element into data object (st, sv), see ReadConfigFile
+// - distinctName is the name used in error messages for parameters, see Parsed
+func (loader *ConfigLoader) readConfigBytes(st reflect.Type, sv reflect.Value, name, distinctName string, b []byte) (err error) {
+	var config interface{}
+	var positions KeyPositions
+
+	// Parse config into map[string]interface{}
+	config, positions, err = decodeConfig(name, b)
 	if err != nil {
-		err = fmt.Errorf("%v [%s]", err, filename)
+		err = fmt.Errorf("%v [%s]", err, errorLocation(name, err))
 		return
 	}
+	return loader.readConfigElement(st, sv, name, distinctName, config, positions)
+}
+
+// readConfigElement Read decoded config, named name, as a single element into data object (st, sv), see readConfigBytes
+func (loader *ConfigLoader) readConfigElement(st reflect.Type, sv reflect.Value, name, distinctName string, config interface{}, positions KeyPositions) (err error) {
+	config, positions = loader.tomlElements(name, config, positions, st)
 
 	// Each file can contain a single element of type 'data'
-	k = reflect.TypeOf(config).Kind().String()
-	if k != "map" {
-		err = fmt.Errorf("contains type %q, must be a JSON element [%s]", k, filename)
-		return
+	if _, ok := config.(map[string]interface{}); !ok {
+		return &ConfigElementError{File: name, Type: valueKind(config), Err: ErrElementNotObject}
 	}
 
-	golog.Log.Debugf("Parsing single element [%s]", filename)
+	golog.Log.Debugf("Parsing single element [%s]", name)
 	parsed := Parsed{
-		FileName:     filename,
-		DistinctName: filepath.Base(filename),
+		FileName:     name,
+		DistinctName: distinctName,
 		ElementMap:   config.(map[string]interface{}),
+		KeyPositions: positions,
 	}
+	return loader.readElement(st, sv, []Parsed{parsed})
+}
+
+// readElement Parse the sources of a single element, in order, into data object (st, sv)
+func (loader *ConfigLoader) readElement(st reflect.Type, sv reflect.Value, parsedArr []Parsed) (err error) {
+	var errList ErrList
 
 	// store in resultMap
 	parsedMap := make(ParsedMap)
-	parsedMap["default"] = []Parsed{parsed}
+	parsedMap["default"] = parsedArr
+
+	// Add environment variables as further sources
+	loader.overlayEnv(st, "", parsedMap)
+
+	// check for conflicting values, a single file can only conflict with environment variables
+	loader.validateParameters(st, parsedMap, false, &errList)
+
+	// Command-line flags take precedence, so are added after checking for conflicts
+	loader.overlayFlags("", parsedMap)
 
 	// Parse dataMap entries into data object (st, sv) fields
-	parseConfig(st, sv, parsedMap, &errList)
+	loader.parseConfig(st, sv, parsedMap, &errList)
 
 	// Compile error list into an error message
 	err = errList.Get()
@@ -98,64 +261,102 @@ func ReadConfigFile(data interface{}, filename string) (err error) {
 }
 
 // ReadConfigFiles Read a list of config files into a map of structs, where 'data' points to struct and idName is field for map key
-// - Can configure an application using one or more JSON files
+// - Can configure an application using one or more JSON, YAML or TOML files, see ConfigFormat
 // - For example, put general settings in one file, credentials in a second file.
+// - Credentials can be encrypted within the file, see EncryptConfigValue and ConfigLoader.Keys
+// - Each element starts from a deep copy of 'data', which is only set to the result if there is a single element
+// - Files can include others, see ConfigIncludeKey, and ReadConfigPaths reads directories and glob patterns
+// - Keys are json tag names, or field names, fields of embedded structs are promoted, as encoding/json reads them, see configFields
+// - See ReadConfigMap for a typed result, and ReadConfigSources to read from an io.Reader, embed.FS or memory
+// - Errors with ErrInvalidTarget or ErrUnknownIDField for a bad 'data' or idName, test with errors.Is
+// - Array items that are not elements are skipped, and reported as *ConfigElementError
 func ReadConfigFiles(data interface{}, idName string, filenames ...string) (resultMap ResultMap, err error) {
-	var b []byte
+	return (&ConfigLoader{}).ReadConfigFiles(data, idName, filenames...)
+}
+
+// ReadConfig Read a single config file into a new T, see ReadConfigFile
+// - loader may be nil to read files only
+func ReadConfig[T any](loader *ConfigLoader, filename string) (config T, err error) {
+	if loader == nil {
+		loader = &ConfigLoader{}
+	}
+	err = loader.ReadConfigFile(&config, filename)
+	return
+}
+
+// ReadConfigMap Read a list of config files into a map of T keyed by field idName, see ReadConfigFiles
+// - Each element is a separately allocated T, so no two share pointers, slices or maps
+// - loader may be nil to read files only
+func ReadConfigMap[T any](loader *ConfigLoader, idName string, filenames ...string) (configMap map[string]T, err error) {
+	var data T
+	var resultMap ResultMap
+
+	if loader == nil {
+		loader = &ConfigLoader{}
+	}
+	resultMap, err = loader.ReadConfigFiles(&data, idName, filenames...)
+
+	configMap = make(map[string]T, len(resultMap))
+	for elementID, v := range resultMap {
+		configMap[elementID] = v.(T)
+	}
+	return
+}
+
+// ReadConfigFiles Read a list of config files using loader options, see ReadConfigFiles
+func (loader *ConfigLoader) ReadConfigFiles(data interface{}, idName string, filenames ...string) (resultMap ResultMap, err error) {
+	resultMap, _, err = loader.ReadConfigFilesProvenance(data, idName, filenames...)
+	return
+}
+
+// ReadConfigFilesProvenance Read a list of config files as ReadConfigFiles, also reporting the source of each value
+func (loader *ConfigLoader) ReadConfigFilesProvenance(data interface{}, idName string, filenames ...string) (resultMap ResultMap, provenanceMap ProvenanceMap, err error) {
+	resultMap, provenanceMap, _, _, err = loader.readConfigFiles(data, idName, filenames...)
+	return
+}
+
+// readConfigFiles Read a list of config files, returning the parsed sources of each element as well as results
+func (loader *ConfigLoader) readConfigFiles(data interface{}, idName string, filenames ...string) (resultMap ResultMap, provenanceMap ProvenanceMap, parsedMap ParsedMap, secretMap map[string]map[string]bool, err error) {
+	return loader.readConfigSources(data, idName, FileSources(filenames...))
+}
+
+// readConfigSources Read a list of config sources, returning the parsed sources of each element as well as results
+// - secretMap records the parameter paths of each element given a decrypted value, see parseConfig
+func (loader *ConfigLoader) readConfigSources(data interface{}, idName string, sources []ConfigSource) (resultMap ResultMap, provenanceMap ProvenanceMap, parsedMap ParsedMap, secretMap map[string]map[string]bool, err error) {
 	var errList ErrList
+	var typedErrs []error
 	var config, v interface{}
 	var file FileDetail
-	var fileDetails []FileDetail
-	var parsedMap ParsedMap
+	var positions KeyPositions
 	var parsedArr []Parsed
-	var k, elementID, idTag string
+	var k, elementID string
+	var idField configField
 	var i int
-	var ok, tag bool
+	var ok bool
 
 	// Make sure data is a pointer to a struct
-	k = reflect.TypeOf(data).Kind().String()
-	if k != "ptr" {
-		err = fmt.Errorf("ReadConfigFiles: 'data' must be ptr, not %s", k)
-		panic(err)
+	st, sv, err := configTarget("ReadConfigFiles", data)
+	if err != nil {
+		return
 	}
-	st := reflect.TypeOf(data).Elem()
-	sv := reflect.ValueOf(data).Elem()
 
-	// Locate field idName and `json:"idTag"`
-	for i = 0; i < st.NumField(); i++ {
-		field := st.Field(i)
-		if field.Name == idName {
-			idTag, tag = field.Tag.Lookup("json")
-			ok = true
-			break
-		}
-	}
+	// Locate field idName, and its key in config files
+	idField, ok = configFieldByName(st, idName)
 	if !ok {
-		err = fmt.Errorf("ReadConfigFiles: 'data' does not contain field %s", idName)
-		panic(err)
+		err = fmt.Errorf("ReadConfigFiles: %w, %s is not a field of %s", ErrUnknownIDField, idName, st)
+		return
 	}
 
-	// Validate file list
-	fileDetails = DistinctFilenames(filenames, &errList)
+	// Read each source, with the files it includes
+	loaded := loadSources(sources, &errList)
 
-	// Each file can contain a single element of type 'data', or an array of these elements
+	// Each source can contain a single element of type 'data', or an array of these elements
 	parsedMap = make(ParsedMap)
-	for _, file = range fileDetails {
-		if b, err = ioutil.ReadFile(file.Name); err != nil {
-			errList = append(errList, fmt.Sprintf("reading file: %v", err))
-			continue
-		}
-		config = nil
-
-		// Parse config file as map[string]interface{}, or slice of these
-		err = json.Unmarshal(b, &config)
-		if err != nil {
-			golog.Log.Debugf("Parsing issue, skipping [%s]", file.Name)
-			errList = append(errList, fmt.Sprintf("%v, skipping [%s]", err, file.Name))
-			continue
-		}
+	for _, source := range loaded {
+		file, config, positions = source.file, source.config, source.positions
+		config, positions = loader.tomlElements(file.Name, config, positions, st)
 
-		k = reflect.TypeOf(config).Kind().String()
+		k = valueKind(config)
 		if k == "map" {
 			golog.Log.Debugf("Parsing single element [%s]", file.Name)
 
@@ -163,16 +364,11 @@ func ReadConfigFiles(data interface{}, idName string, filenames ...string) (resu
 				FileName:     file.Name,
 				DistinctName: file.DistinctName,
 				ElementMap:   config.(map[string]interface{}),
+				KeyPositions: positions,
 			}
 
 			// Find element Id by json tag or field name
-			v = nil
-			if tag {
-				v = parsed.ElementMap[idTag]
-			}
-			if v == nil {
-				v = parsed.ElementMap[idName]
-			}
+			v, _ = loader.lookupParam(idField, parsed.ElementMap)
 			if v == nil {
 				errList = append(errList, fmt.Sprintf("required id parameter %s not found, skipping [%s]",
 					idName, file.Name))
@@ -194,25 +390,27 @@ func ReadConfigFiles(data interface{}, idName string, filenames ...string) (resu
 
 			// Config file contains an array of elements of type 'data'
 			for i, v = range config.([]interface{}) {
+				element, isMap := v.(map[string]interface{})
+				if !isMap {
+					elementErr := &ConfigElementError{File: file.DistinctName, Position: i + 1, Type: valueKind(v), Err: ErrElementNotObject}
+					errList.Addf("%v, skipping", elementErr)
+					typedErrs = append(typedErrs, elementErr)
+					continue
+				}
 
 				parsed := Parsed{
 					FileName:     file.Name,
 					DistinctName: file.DistinctName,
 					Position:     i + 1,
-					ElementMap:   v.(map[string]interface{}),
+					ElementMap:   element,
+					KeyPositions: positions.sub(fmt.Sprintf("[%d]", i)),
 				}
 
 				// Find element Id by json tag or field name
-				v = nil
-				if tag {
-					v = parsed.ElementMap[idTag]
-				}
-				if v == nil {
-					v = parsed.ElementMap[idName]
-				}
+				v, _ = loader.lookupParam(idField, parsed.ElementMap)
 				if v == nil {
-					errList = append(errList, fmt.Sprintf("required id parameter %s not found, skipping [%s:elem#%d]",
-						idName, file.Name, parsed.Position))
+					errList = append(errList, fmt.Sprintf("required id parameter %s not found, skipping [%s]",
+						idName, parsed.at(st, "")))
 					continue
 				}
 				elementID = fmt.Sprintf("%v", v)
@@ -227,305 +425,748 @@ func ReadConfigFiles(data interface{}, idName string, filenames ...string) (resu
 				parsedMap[elementID] = parsedArr
 			}
 		} else {
-			errList = append(errList, fmt.Sprintf("parsing config: unrecognized JSON type %q [%s]", k, file.Name))
+			elementErr := &ConfigElementError{File: file.Name, Type: k, Err: ErrElementNotObject}
+			errList.Addf("%v, skipping", elementErr)
+			typedErrs = append(typedErrs, elementErr)
 			continue
 		}
 	}
 
+	// Add environment variables as further sources
+	loader.overlayEnv(st, idName, parsedMap)
+
 	// check for conflicting values and unused parameters
-	validateParameters(st, parsedMap, &errList)
+	loader.validateParameters(st, parsedMap, true, &errList)
+
+	// Command-line flags take precedence, so are added after checking for conflicts
+	loader.overlayFlags(idName, parsedMap)
 
 	// collapse each element into a single data object and load into resultMap
-	resultMap = parseConfig(st, sv, parsedMap, &errList)
+	resultMap, provenanceMap, secretMap = loader.parseConfig(st, sv, parsedMap, &errList)
 
-	// Compile error list into an error message
-	err = errList.Get()
+	// Compile error list into an error message, that can still be tested for typed errors
+	err = withErrors(errList.Get(), typedErrs)
 
 	golog.Log.Debugf("Parsed %d distinct configurations", len(parsedMap))
 	return
 }
 
+// location Name where this element was found, for error messages
+// - DistinctName, with element # appended if file contains an array of elements
+func (parsed Parsed) location() string {
+	if parsed.Position == 0 {
+		return parsed.DistinctName
+	}
+	return fmt.Sprintf("%s:elem#%d", parsed.DistinctName, parsed.Position)
+}
+
+// parseContext Identifies the element and file being parsed, so errors can be reported against them
+// - set records the source of each parameter path given a value, so missing parameters can be found
+// - override is true while parsing a source that takes precedence whatever the merge policy
+// - vars holds the element's raw parameter values, for ${...} references, see interpolate
+// - source is the source being parsed, so errors can give the line and column of a parameter, see at
+// - secrets records the parameter paths given a decrypted value, so their values are never shown
+// - tag is the struct tag of the field being parsed, also used for the items of its slices and maps
+type parseContext struct {
+	loader    *ConfigLoader
+	st        reflect.Type
+	source    Parsed
+	elementID string
+	filename  string
+	override  bool
+	errList   *ErrList
+	set       map[string]string
+	vars      map[string]interface{}
+	secrets   map[string]bool
+	tag       reflect.StructTag
+}
+
+// at Location of parameter param in the current source for error messages, with line and column if known
+func (ctx *parseContext) at(param string) string {
+	if ctx.source.KeyPositions == nil {
+		return ctx.filename
+	}
+	return ctx.source.at(ctx.st, param)
+}
+
 // parseConfig Parse config dataMap entries corresponding to data object (st, sv) fields
 // - Allows values to be numbers, strings, dates, datetimes, or durations
+// - Or any type implementing encoding.TextUnmarshaler, see parseValue
+// - Nested structs, pointers, slices and maps are parsed recursively
 // - Numbers can be specified as a string or value
+// - String values have ${param}, ${env:NAME} and ${file:path} references replaced before conversion, see interpolate
+// - Values starting enc:v1: are decrypted with the loader's KeyProvider, see EncryptConfigValue
+// - Parameters missing from every source are set from their `default:"..."` tag, see applyDefaults
+// - Values are then checked against their `validate:"..."` tag, see validateRules
+// - Each element is parsed into a fresh copy of sv, so results never share pointers, slices or maps
+// - If there is a single element, sv is also set to it
+// - Sources are applied in order, resolved by the loader's MergePolicy, and recorded in provenanceMap
+// - secretMap records the parameter paths of each element given a decrypted value, so their values are never shown
 // - Errors if extra parameters configured
-func parseConfig(st reflect.Type, sv reflect.Value, parsedMap ParsedMap, errList *ErrList) (resultMap ResultMap) {
-	var err error
-	var elementID, filename, tagName, paramValue, paramType string
+func (loader *ConfigLoader) parseConfig(st reflect.Type, sv reflect.Value, parsedMap ParsedMap, errList *ErrList) (resultMap ResultMap, provenanceMap ProvenanceMap, secretMap map[string]map[string]bool) {
+	var elementID string
 	var v interface{}
 	var parsedArr []Parsed
-	var dur time.Duration
-	var date time.Time
-	var f float64
-	var n int64
-	var i int
-	var ok, clear bool
-
-	// Map for json param names to tag names
-	param2tag := make(map[string]string)
-	for i = 0; i < st.NumField(); i++ {
-		param := st.Field(i)
-		tagName, ok = param.Tag.Lookup("json")
-		if ok {
-			param2tag[param.Name] = tagName
-		}
-	}
-
-	// If multiple results, we will have to clear 'data' object each iteration
-	clear = len(parsedMap) > 1
+	var ok bool
 
 	resultMap = make(ResultMap)
+	provenanceMap = make(ProvenanceMap)
+	secretMap = make(map[string]map[string]bool)
 	for elementID, parsedArr = range parsedMap {
 
-		// Make map of parameter names that will need to be reset after parsing element
-		clearParamMap := make(map[string]bool)
+		// Start each element from its own copy of the data object
+		ev := reflect.New(st).Elem()
+		ev.Set(copyValue(sv))
+
+		ctx := &parseContext{
+			loader:    loader,
+			st:        st,
+			elementID: elementID,
+			errList:   errList,
+			set:       make(map[string]string),
+			vars:      loader.interpolationVars(st, parsedArr),
+			secrets:   make(map[string]bool),
+		}
+		var searched []string
 
 		for _, parsed := range parsedArr {
-			if parsed.Position == 0 {
-				filename = parsed.DistinctName
-			} else {
-				filename = fmt.Sprintf("%s:elem#%d", parsed.DistinctName, parsed.Position)
-			}
+			ctx.source = parsed
+			ctx.filename = parsed.location()
+			ctx.override = parsed.Override
+			searched = append(searched, ctx.filename)
 
 			// Iterate through element data fields, parse into correct type
-			for i = 0; i < st.NumField(); i++ {
-				param := st.Field(i)
-
-				// lookup in ElementMap by tag name first, then by param name
-				tagName, ok = param2tag[param.Name]
-				if ok {
-					v, ok = parsed.ElementMap[tagName]
-				}
-				if !ok {
-					v, ok = parsed.ElementMap[param.Name]
-				}
-
+			for _, param := range configFields(st) {
+				v, ok = loader.lookupParam(param, parsed.ElementMap)
 				if ok {
-					paramValue = fmt.Sprintf("%v", v)
-					paramType = param.Type.Name()
-					clearParamMap[param.Name] = true
-
-					switch paramType {
-					case "string":
-						sv.Field(i).SetString(paramValue)
-					case "float64":
-						f, err = strconv.ParseFloat(paramValue, 64)
-						if err != nil {
-							errList.Addf("setting for %s invalid, parameter %s: float %s [%s]",
-								elementID, param.Name, paramValue, filename)
-							continue
-						}
-						sv.Field(i).SetFloat(f)
-					case "int", "int64":
-						n, err = strconv.ParseInt(paramValue, 10, 64)
-						if err != nil {
-							errList.Addf("setting for %s invalid, parameter %s: integer %s [%s]",
-								elementID, param.Name, paramValue, filename)
-							continue
-						}
-						sv.Field(i).SetInt(n)
-					case "bool":
-						ok, err = strconv.ParseBool(paramValue)
-						if err != nil {
-							errList.Addf("setting for %s invalid, parameter %s: boolean %s [%s]",
-								elementID, param.Name, paramValue, filename)
-							continue
-						}
-						sv.Field(i).SetBool(ok)
-					case "Duration":
-						dur, err = time.ParseDuration(paramValue)
-						if err != nil {
-							errList.Addf("setting for %s invalid, parameter %s: duration %s [%s]",
-								elementID, param.Name, paramValue, filename)
-							continue
-						}
-						sv.Field(i).Set(reflect.ValueOf(dur))
-					case "Time":
-						date, err = time.Parse("2006-01-02T15:04:05Z", paramValue)
-						if err != nil {
-							date, err = time.Parse("2006-01-02", paramValue)
-						}
-						if err != nil {
-							errList.Addf("setting for %s invalid, parameter %s: date %s [%s]",
-								elementID, param.Name, paramValue, filename)
-							continue
-						}
-						sv.Field(i).Set(reflect.ValueOf(date))
-					default:
-						errList.Addf("setting for %s invalid, parameter %s: unsupported type %s [%s]",
-							elementID, param.Name, paramType, filename)
-						continue
-					}
+					fv, _ := fieldValue(ev, param, true)
+					ctx.tag = param.Tag
+					ctx.parseValue(fv, v, param.Name)
 				}
 			}
 		}
+
+		// Fill in defaults, and check required parameters were found
+		ctx.source = Parsed{}
+		ctx.filename = "default"
+		ctx.override = false
+		ctx.applyDefaults(st, ev, "", searched)
+
+		// Check values against `validate:"..."` rules
+		ctx.validateRules(st, ev, "")
+
+		// Record where each value came from
+		provenanceMap[elementID] = make(map[string]string)
+		ctx.provenance(st, "", provenanceMap[elementID])
+		secretMap[elementID] = ctx.secrets
+
 		// Store data object in resultMap
-		resultMap[elementID] = sv.Interface()
+		resultMap[elementID] = ev.Interface()
+		if len(parsedMap) == 1 {
+			sv.Set(ev)
+		}
+	}
+	return
+}
+
+// copyValue Make a deep copy of v, so the copy shares no pointers, slices or maps with it
+func copyValue(v reflect.Value) (c reflect.Value) {
+	var i int
 
-		// Clear data object for next element Id
-		if clear {
-			err = clearConfig(st, sv, clearParamMap)
-			if err != nil {
-				golog.Log.Warningf("Error clear data object: %v", err)
+	c = reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			c.Set(reflect.New(v.Type().Elem()))
+			c.Elem().Set(copyValue(v.Elem()))
+		}
+	case reflect.Struct:
+		c.Set(v)
+		for i = 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(copyValue(v.Field(i)))
+			}
+		}
+	case reflect.Slice:
+		if !v.IsNil() {
+			c.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+			for i = 0; i < v.Len(); i++ {
+				c.Index(i).Set(copyValue(v.Index(i)))
+			}
+		}
+	case reflect.Map:
+		if !v.IsNil() {
+			c.Set(reflect.MakeMapWithSize(v.Type(), v.Len()))
+			iter := v.MapRange()
+			for iter.Next() {
+				c.SetMapIndex(iter.Key(), copyValue(iter.Value()))
 			}
 		}
+	default:
+		c.Set(v)
 	}
 	return
 }
 
-// clearConfig ...
-func clearConfig(st reflect.Type, sv reflect.Value, clearParamMap map[string]bool) (err error) {
-	var paramType string
-	var zeroD time.Duration
-	var zeroT time.Time
+// Types parsed from their own string representation rather than by reflect.Kind
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// parseValue Parse config value v from the current source into data object field fv, see setValue
+// - param is the dotted parameter path, recorded in set along with the source
+// - Values already given by an earlier source are resolved by the loader's MergePolicy
+func (ctx *parseContext) parseValue(fv reflect.Value, v interface{}, param string) {
+	var merge, ok bool
+
+	// Resolve values already given by an earlier source, set is nil within slices and maps
+	if ctx.set != nil {
+		prev, found := ctx.set[param]
+		if found && !ctx.override && ctx.loader.Merge == MergeFirstWins && !ctx.appends(fv) && !isNested(fv.Type()) {
+			return
+		}
+		merge = found && ctx.appends(fv)
+		if merge && prev != ctx.filename {
+			ctx.set[param] = prev + "," + ctx.filename
+		} else {
+			ctx.set[param] = ctx.filename
+		}
+	}
+
+	if s, isString := v.(string); isString {
+		if strings.HasPrefix(s, ConfigSecretPrefix) {
+			if v, ok = ctx.decrypt(s, param); !ok {
+				return
+			}
+		} else if strings.Contains(s, "${") {
+			if v, ok = ctx.interpolate(s, param, nil); !ok {
+				return
+			}
+		}
+	}
+	ctx.setValue(fv, v, param, merge)
+}
+
+// setValue Convert config value v into data object field fv, where param is the dotted parameter path
+// - Types are matched by reflect.Kind, so named types (e.g. type Port int) parse as their underlying kind
+// - Integers and floats are checked for overflow of the field's size
+// - Types implementing encoding.TextUnmarshaler are decoded through UnmarshalText
+// - Times and durations are parsed by parseTime and parseDuration, using the field's layout and unit tags
+// - Nested structs are read from a config element, slices from an array, maps from an element of key-values
+// - Pointers are allocated as needed, null leaves the field at its zero value
+// - If merge, slices are appended to and maps merged into the field's current value
+func (ctx *parseContext) setValue(fv reflect.Value, v interface{}, param string, merge bool) {
+	var err error
+	var paramValue, shown string
+	var dur time.Duration
+	var date time.Time
+	var f float64
+	var n int64
+	var u uint64
 	var i int
 	var ok bool
 
-	// Iterate through data fields, clear settings
-	for i = 0; i < st.NumField(); i++ {
-		param := st.Field(i)
+	if v == nil {
+		fv.Set(reflect.Zero(fv.Type()))
+		return
+	}
 
-		ok = clearParamMap[param.Name]
-		if ok {
-			paramType = param.Type.Name()
-
-			switch paramType {
-			case "string":
-				sv.Field(i).SetString("")
-			case "float64":
-				sv.Field(i).SetFloat(0)
-			case "int", "int64":
-				sv.Field(i).SetInt(0)
-			case "bool":
-				sv.Field(i).SetBool(false)
-			case "Duration":
-				sv.Field(i).Set(reflect.ValueOf(zeroD))
-			case "Time":
-				sv.Field(i).Set(reflect.ValueOf(zeroT))
-			default:
-				err = fmt.Errorf("unsupported type %s [%s]", paramType, param.Name)
+	// Print JSON numbers without exponent, so large integers parse
+	if f, ok = v.(float64); ok {
+		paramValue = strconv.FormatFloat(f, 'f', -1, 64)
+	} else {
+		paramValue = fmt.Sprintf("%v", v)
+	}
+
+	// Decrypted secrets are never shown in error messages
+	shown = paramValue
+	if ctx.secrets[param] {
+		shown = secretMask
+	}
+
+	switch {
+	case fv.Type() == durationType:
+		dur, err = parseDuration(paramValue, ctx.tag)
+		if err != nil {
+			ctx.errList.Addf("setting for %s invalid, parameter %s: duration %s [%s]",
+				ctx.elementID, param, shown, ctx.at(param))
+			return
+		}
+		fv.Set(reflect.ValueOf(dur))
+		return
+	case fv.Type() == timeType:
+		date, err = ctx.loader.parseTime(paramValue, ctx.tag)
+		if err != nil {
+			ctx.errList.Addf("setting for %s invalid, parameter %s: date %s [%s]",
+				ctx.elementID, param, shown, ctx.at(param))
+			return
+		}
+		fv.Set(reflect.ValueOf(date))
+		return
+	case fv.Kind() != reflect.Ptr && reflect.PtrTo(fv.Type()).Implements(textUnmarshalerType) && fv.CanAddr():
+		err = fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(paramValue))
+		if err != nil {
+			ctx.errList.Addf("setting for %s invalid, parameter %s: %s %s [%s]",
+				ctx.elementID, param, fv.Type(), shown, ctx.at(param))
+		}
+		return
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(paramValue)
+	case reflect.Float32, reflect.Float64:
+		f, err = strconv.ParseFloat(paramValue, 64)
+		if err != nil {
+			ctx.errList.Addf("setting for %s invalid, parameter %s: float %s [%s]",
+				ctx.elementID, param, shown, ctx.at(param))
+			return
+		}
+		if fv.OverflowFloat(f) {
+			ctx.errList.Addf("setting for %s invalid, parameter %s: float %s overflows %s [%s]",
+				ctx.elementID, param, shown, fv.Kind(), ctx.at(param))
+			return
+		}
+		fv.SetFloat(f)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err = strconv.ParseInt(paramValue, 10, 64)
+		if err != nil {
+			if errors.Is(err, strconv.ErrRange) {
+				ctx.errList.Addf("setting for %s invalid, parameter %s: integer %s overflows %s [%s]",
+					ctx.elementID, param, shown, fv.Kind(), ctx.at(param))
+				return
+			}
+			ctx.errList.Addf("setting for %s invalid, parameter %s: integer %s [%s]",
+				ctx.elementID, param, shown, ctx.at(param))
+			return
+		}
+		if fv.OverflowInt(n) {
+			ctx.errList.Addf("setting for %s invalid, parameter %s: integer %s overflows %s [%s]",
+				ctx.elementID, param, shown, fv.Kind(), ctx.at(param))
+			return
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err = strconv.ParseUint(paramValue, 10, 64)
+		if err != nil {
+			if errors.Is(err, strconv.ErrRange) {
+				ctx.errList.Addf("setting for %s invalid, parameter %s: unsigned integer %s overflows %s [%s]",
+					ctx.elementID, param, shown, fv.Kind(), ctx.at(param))
 				return
 			}
+			ctx.errList.Addf("setting for %s invalid, parameter %s: unsigned integer %s [%s]",
+				ctx.elementID, param, shown, ctx.at(param))
+			return
+		}
+		if fv.OverflowUint(u) {
+			ctx.errList.Addf("setting for %s invalid, parameter %s: unsigned integer %s overflows %s [%s]",
+				ctx.elementID, param, shown, fv.Kind(), ctx.at(param))
+			return
+		}
+		fv.SetUint(u)
+	case reflect.Bool:
+		ok, err = strconv.ParseBool(paramValue)
+		if err != nil {
+			ctx.errList.Addf("setting for %s invalid, parameter %s: boolean %s [%s]",
+				ctx.elementID, param, shown, ctx.at(param))
+			return
+		}
+		fv.SetBool(ok)
+	case reflect.Interface:
+		if !reflect.TypeOf(v).AssignableTo(fv.Type()) {
+			ctx.errList.Addf("setting for %s invalid, parameter %s: %s %s [%s]",
+				ctx.elementID, param, fv.Type(), shown, ctx.at(param))
+			return
+		}
+		fv.Set(reflect.ValueOf(v))
+	case reflect.Ptr:
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		ctx.setValue(fv.Elem(), v, param, merge)
+	case reflect.Struct:
+		m, isMap := v.(map[string]interface{})
+		if !isMap {
+			ctx.errList.Addf("setting for %s invalid, parameter %s: element %s [%s]",
+				ctx.elementID, param, shown, ctx.at(param))
+			return
+		}
+		for _, field := range configFields(fv.Type()) {
+			if v, ok = ctx.loader.lookupParam(field, m); ok {
+				ev, _ := fieldValue(fv, field, true)
+				ctx.tag = field.Tag
+				ctx.parseValue(ev, v, param+"."+field.Name)
+			}
+		}
+	case reflect.Slice:
+		arr, isArr := v.([]interface{})
+		if !isArr {
+			ctx.errList.Addf("setting for %s invalid, parameter %s: array %s [%s]",
+				ctx.elementID, param, shown, ctx.at(param))
+			return
+		}
+		elemCtx := *ctx
+		elemCtx.set = nil
+		slice := reflect.MakeSlice(fv.Type(), len(arr), len(arr))
+		for i = range arr {
+			elemCtx.parseValue(slice.Index(i), arr[i], fmt.Sprintf("%s[%d]", param, i))
+		}
+		if merge {
+			slice = reflect.AppendSlice(copyValue(fv), slice)
+		}
+		fv.Set(slice)
+	case reflect.Map:
+		m, isMap := v.(map[string]interface{})
+		if !isMap {
+			ctx.errList.Addf("setting for %s invalid, parameter %s: map %s [%s]",
+				ctx.elementID, param, shown, ctx.at(param))
+			return
+		}
+		mapValue := reflect.MakeMapWithSize(fv.Type(), len(m))
+		if merge {
+			mapValue = copyValue(fv)
+			if mapValue.IsNil() {
+				mapValue = reflect.MakeMapWithSize(fv.Type(), len(m))
+			}
+		}
+		elemCtx := *ctx
+		elemCtx.set = nil
+		for key, item := range m {
+			kv := reflect.New(fv.Type().Key()).Elem()
+			elemCtx.parseValue(kv, key, param+"."+key)
+			if merge && !ctx.override && ctx.loader.Merge == MergeFirstWins && mapValue.MapIndex(kv).IsValid() {
+				continue
+			}
+			ev := reflect.New(fv.Type().Elem()).Elem()
+			elemCtx.parseValue(ev, item, param+"."+key)
+			mapValue.SetMapIndex(kv, ev)
 		}
+		fv.Set(mapValue)
+	default:
+		ctx.errList.Addf("setting for %s invalid, parameter %s: unsupported type %s [%s]",
+			ctx.elementID, param, fv.Type(), ctx.at(param))
 	}
+}
 
-	return
+// appends Is field fv a slice or map to be combined with values from earlier sources
+func (ctx *parseContext) appends(fv reflect.Value) bool {
+	if ctx.override || ctx.loader.Merge == MergeError {
+		return false
+	}
+	switch fv.Kind() {
+	case reflect.Slice:
+		return ctx.loader.MergeSlices == MergeAppend
+	case reflect.Map:
+		return ctx.loader.MergeMaps == MergeAppend
+	default:
+		return false
+	}
+}
+
+// isNested Is type t a struct, or pointer to a struct, whose fields are parsed individually
+func isNested(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != timeType && !reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// provenance Load the source of each data object (st) parameter given a value into report
+// - Nested struct parameters are listed individually, by dotted parameter path
+// - Nested structs are only searched if a parameter within them was set, so self-referential structs end
+func (ctx *parseContext) provenance(st reflect.Type, prefix string, report map[string]string) {
+	var param, filename string
+	var ok bool
+
+	for _, field := range configFields(st) {
+		param = prefix + field.Name
+		if isNested(field.Type) {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			for setParam := range ctx.set {
+				if strings.HasPrefix(setParam, param+".") {
+					ctx.provenance(fieldType, param+".", report)
+					break
+				}
+			}
+			continue
+		}
+		filename, ok = ctx.set[param]
+		if ok {
+			report[param] = filename
+		}
+	}
+}
+
+// applyDefaults Set data object (st, sv) fields that no source gave a value from their `default:"..."` tag
+// - Defaults are converted by parseValue, exactly as a config file value would be
+// - Fields tagged `required:"true"` without a value or default are errors, naming each file searched
+// - Nested structs are searched recursively, unless they are a nil pointer
+func (ctx *parseContext) applyDefaults(st reflect.Type, sv reflect.Value, prefix string, searched []string) {
+	var param, defaultValue string
+	var ok bool
+
+	for _, field := range configFields(st) {
+		param = prefix + field.Name
+		fv, _ := fieldValue(sv, field, true)
+
+		if _, ok = ctx.set[param]; !ok {
+			defaultValue, ok = field.Tag.Lookup("default")
+			if ok {
+				ctx.tag = field.Tag
+				ctx.parseValue(fv, defaultValue, param)
+				continue
+			}
+			if field.Tag.Get("required") == "true" {
+				ctx.errList.Addf("required setting for %s missing, parameter %s [%s]",
+					ctx.elementID, param, strings.Join(searched, ","))
+				continue
+			}
+		}
+
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+		if isNested(fv.Type()) {
+			ctx.applyDefaults(fv.Type(), fv, param+".", searched)
+		}
+	}
 }
 
 // validateParameters Check data object (st) fields for any conflicting result map values
-func validateParameters(st reflect.Type, parsedMap ParsedMap, errList *ErrList) {
+// - Nested struct parameters are compared, and reported, by dotted parameter path
+// - Conflicts are only errors with the MergeError policy, otherwise they are resolved by parseConfig
+// - Values are compared once converted to the field's type, so environment variable 1.50 agrees with file value 1.5
+// - Element parameters that don't match any field are reported by the loader's UnknownKeyPolicy
+// - With UnknownKeysDefault, they are only reported if checkUnused
+func (loader *ConfigLoader) validateParameters(st reflect.Type, parsedMap ParsedMap, checkUnused bool, errList *ErrList) {
 	var filenames []string
-	var elementID, key, filename, tagName, paramName, paramValue string
+	var elementID, filename, paramName, paramValue, message string
 	var parsedArr []Parsed
 	var paramValuesMap map[string][]string
+	var paramTexts map[string]string
 	var v interface{}
-	var i int
 	var ok bool
 
-	// Maps for json tag names <-> param names, and valid param names
-	tag2param := make(map[string]string)
-	param2tag := make(map[string]string)
-	validparam := make(map[string]bool)
-	for i = 0; i < st.NumField(); i++ {
-		param := st.Field(i)
-		tagName, ok = param.Tag.Lookup("json")
-		if ok {
-			tag2param[tagName] = param.Name
-			param2tag[param.Name] = tagName
+	policy := loader.UnknownKeys
+	if policy == UnknownKeysDefault {
+		policy = UnknownKeysIgnore
+		if checkUnused {
+			policy = UnknownKeysStrict
 		}
-		validparam[param.Name] = true
 	}
 
 	// Validate parameters for each element
 	for elementID, parsedArr = range parsedMap {
 
-		// make list of values for each parameter, with filenames found in
+		// make list of values for each parameter, with filenames found in, and the text of each value as given
 		elementParamValuesMap := make(map[string]map[string][]string)
+		elementParamTextMap := make(map[string]map[string]string)
 
 		// make map of parameter names, with filenames found in, to see what isn't used
 		unusedParamMap := make(map[string][]string)
 
 		// check across all files parsed
 		for _, parsed := range parsedArr {
-			if parsed.Position == 0 {
-				filename = parsed.DistinctName
-			} else {
-				filename = fmt.Sprintf("%s:elem#%d", parsed.DistinctName, parsed.Position)
-			}
+			params := make(map[string]interface{})
+			unused := make(map[string]bool)
+			loader.flattenParams(st, "", parsed.ElementMap, params, unused)
 
 			// load elementParamValuesMap to identify possible conflicting values
-			for i = 0; i < st.NumField(); i++ {
-				param := st.Field(i)
-				// lookup in config dataMap by tag name first, then by param name
-				tagName, ok = param2tag[param.Name]
-				if ok {
-					v, ok = parsed.ElementMap[tagName]
-				}
-				if !ok {
-					v, ok = parsed.ElementMap[param.Name]
+			for paramName, v = range params {
+				paramValue = loader.comparableValue(st, paramName, v)
+				filename = parsed.at(st, paramName)
+				if paramTexts, ok = elementParamTextMap[paramName]; !ok {
+					paramTexts = make(map[string]string)
+					elementParamTextMap[paramName] = paramTexts
 				}
-				if ok {
-					paramValue = fmt.Sprintf("%v", v)
-
-					// make list all filenames for each parameter value
-					paramValuesMap, ok = elementParamValuesMap[param.Name]
-					if !ok {
-						paramValuesMap = make(map[string][]string)
-						filenames = []string{filename}
-					} else {
-						filenames, ok = paramValuesMap[paramValue]
-						if ok {
-							filenames = append(filenames, filename)
-						} else {
-							filenames = []string{filename}
-						}
-					}
-					paramValuesMap[paramValue] = filenames
-					elementParamValuesMap[param.Name] = paramValuesMap
+				if _, ok = paramTexts[paramValue]; !ok {
+					paramTexts[paramValue] = fmt.Sprintf("%v", v)
 				}
-			}
 
-			// load unusedParamMap to identify possible unused parameters
-			for key = range parsed.ElementMap {
-				// lookup element key by tag name first
-				paramName, ok = tag2param[key]
+				// make list all filenames for each parameter value
+				paramValuesMap, ok = elementParamValuesMap[paramName]
 				if !ok {
-					paramName = key
-				}
-				if !validparam[paramName] {
-					filenames, ok = unusedParamMap[paramName]
+					paramValuesMap = make(map[string][]string)
+					filenames = []string{filename}
+				} else {
+					filenames, ok = paramValuesMap[paramValue]
 					if ok {
 						filenames = append(filenames, filename)
 					} else {
 						filenames = []string{filename}
 					}
-					unusedParamMap[paramName] = filenames
 				}
+				paramValuesMap[paramValue] = filenames
+				elementParamValuesMap[paramName] = paramValuesMap
+			}
+
+			// load unusedParamMap to identify possible unused parameters
+			for paramName = range unused {
+				filename = parsed.at(st, paramName)
+				filenames, ok = unusedParamMap[paramName]
+				if ok {
+					filenames = append(filenames, filename)
+				} else {
+					filenames = []string{filename}
+				}
+				unusedParamMap[paramName] = filenames
 			}
 		}
 
 		// List errors for conflicting values
 		for paramName, paramValuesMap = range elementParamValuesMap {
 			// if there are more than one value, it means settings conflict
-			if len(paramValuesMap) > 1 {
+			if len(paramValuesMap) > 1 && loader.Merge == MergeError {
 				var conflicts []string
 				for paramValue, filenames = range paramValuesMap {
 					conflicts = append(conflicts, fmt.Sprintf("%q [%s]",
-						paramValue, strings.Join(filenames, ",")))
+						elementParamTextMap[paramName][paramValue], strings.Join(filenames, ",")))
 				}
 				errList.Addf("settings for %s conflict, parameter %s: %s",
 					elementID, paramName, strings.Join(conflicts, " != "))
 			}
 		}
 
-		// List  errors for unused parameters
+		// Report unused parameters by the loader's UnknownKeyPolicy, suggesting the closest field
+		if policy == UnknownKeysIgnore {
+			continue
+		}
 		for paramName, filenames = range unusedParamMap {
 			if len(filenames) == 1 {
-				errList.Addf("unused setting for %s, parameter %s [%s]", elementID, paramName, filenames[0])
+				message = fmt.Sprintf("unused setting for %s, parameter %s%s [%s]",
+					elementID, paramName, suggestParam(st, paramName), filenames[0])
 			} else {
-				errList.Addf("unused settings for %s, parameter %s: %d occurences [%s]",
-					elementID, paramName, len(filenames), strings.Join(filenames, ","))
+				message = fmt.Sprintf("unused settings for %s, parameter %s%s: %d occurences [%s]",
+					elementID, paramName, suggestParam(st, paramName), len(filenames), strings.Join(filenames, ","))
 			}
+			if policy == UnknownKeysLenient {
+				golog.Log.Warning(message)
+			} else {
+				errList.Add(message)
+			}
+		}
+	}
+}
+
+// comparableValue Text of value v for dotted parameter path param of data object (st), once converted to the field's type
+// - Values that don't convert, and encrypted values and ${...} references, which are only resolved when parsed, are compared as given
+func (loader *ConfigLoader) comparableValue(st reflect.Type, param string, v interface{}) string {
+	var errList ErrList
+	var field configField
+	var ok bool
+
+	text := fmt.Sprintf("%v", v)
+	if s, isString := v.(string); isString && (strings.HasPrefix(s, ConfigSecretPrefix) || strings.Contains(s, "${")) {
+		return text
+	}
+
+	// Find the field, allocating any pointers to nested structs on the way
+	fv := reflect.New(st).Elem()
+	for _, name := range strings.Split(param, ".") {
+		if fv.Kind() == reflect.Ptr {
+			fv.Set(reflect.New(fv.Type().Elem()))
+			fv = fv.Elem()
+		}
+		if field, ok = configFieldByName(fv.Type(), name); !ok {
+			return text
+		}
+		fv, _ = fieldValue(fv, field, true)
+	}
+
+	ctx := &parseContext{loader: loader, st: st, errList: &errList, secrets: make(map[string]bool), tag: field.Tag}
+	ctx.setValue(fv, v, param, false)
+	if len(errList) > 0 {
+		return text
+	}
+	for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+		fv = fv.Elem()
+	}
+	return fmt.Sprintf("%v", fv.Interface())
+}
+
+// unusedItemKeys Add the keys of struct items of slice or map value v, of type t, which don't match any field to unused
+// - Items are named by parameter path param with index or map key, e.g. Servers[0] or Pools.read
+func (loader *ConfigLoader) unusedItemKeys(t reflect.Type, param string, v interface{}, unused map[string]bool) {
+	var itemParam string
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	items := make(map[string]interface{})
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		arr, _ := v.([]interface{})
+		for i, item := range arr {
+			items[fmt.Sprintf("%s[%d]", param, i)] = item
+		}
+	case reflect.Map:
+		m, _ := v.(map[string]interface{})
+		for key, item := range m {
+			items[param+"."+key] = item
+		}
+	default:
+		return
+	}
+
+	itemType := t.Elem()
+	for itemType.Kind() == reflect.Ptr {
+		itemType = itemType.Elem()
+	}
+	for itemParam, v = range items {
+		if m, isMap := v.(map[string]interface{}); isMap && isNested(itemType) {
+			loader.flattenParams(itemType, itemParam+".", m, make(map[string]interface{}), unused)
+		} else {
+			loader.unusedItemKeys(itemType, itemParam, v, unused)
+		}
+	}
+}
+
+// flattenParams Load element map m values for data object (st) fields into params, keyed by parameter path
+// - Nested structs are flattened, so each nested parameter is compared separately, e.g. DB.Host
+// - Keys which don't match any field are added to unused, including those of structs in slices and maps, e.g. Servers[0].hots
+func (loader *ConfigLoader) flattenParams(st reflect.Type, prefix string, m map[string]interface{}, params map[string]interface{}, unused map[string]bool) {
+	var key string
+	var v interface{}
+	var ok bool
+
+	fields := configFields(st)
+	for _, param := range fields {
+		// lookup in config dataMap by tag name first, then by param name
+		v, ok = loader.lookupParam(param, m)
+		if !ok {
+			continue
+		}
+		nested, isMap := v.(map[string]interface{})
+		nestedType := param.Type
+		if nestedType.Kind() == reflect.Ptr {
+			nestedType = nestedType.Elem()
+		}
+		if isMap && isNested(nestedType) {
+			loader.flattenParams(nestedType, prefix+param.Name+".", nested, params, unused)
+		} else {
+			params[prefix+param.Name] = v
+			loader.unusedItemKeys(nestedType, prefix+param.Name, v, unused)
+		}
+	}
+
+	for key = range m {
+		ok = false
+		for _, param := range fields {
+			if loader.matchesField(key, param) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			unused[prefix+key] = true
 		}
 	}
 }
@@ -0,0 +1,190 @@
+package goutils
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// ConfigDiff Differences between two ResultMaps, one entry per element Id added, removed or changed
+type ConfigDiff []ElementDiff
+
+// ElementDiff Differences for one element Id
+// - Added and Removed elements list no parameters, Changed elements list each parameter that differs
+type ElementDiff struct {
+	ElementID string
+	Added     bool
+	Removed   bool
+	Params    []ParamDiff
+}
+
+// ParamDiff Before and after values of one parameter, by dotted parameter path
+// - Secret parameters that are set show as (secret), see SecretNamePatterns
+type ParamDiff struct {
+	Param  string
+	Before interface{}
+	After  interface{}
+}
+
+// Diff Compare old and new ResultMaps, from ReadConfigFiles, element by element in element Id order
+// - Elements are matched by Id, those only in newMap are Added, those only in oldMap Removed
+// - Parameters are compared in struct field order, nested struct parameters by dotted path
+// - Secret values are redacted, so a diff can be logged or printed before rolling out a change
+// - Pointers to elements are compared as the elements, a nil element as one with no parameters set
+// - Elements that can't be compared by parameter, of different types or not structs, are shown masked as one change
+// - So are structs nested within a struct of the same type, see paramPaths
+func Diff(oldMap, newMap ResultMap) (diff ConfigDiff) {
+	var elementIDs []string
+	var oldVal, newVal, before, after reflect.Value
+	var st reflect.Type
+	var okBefore, okAfter bool
+
+	seen := make(map[string]bool)
+	for elementID := range oldMap {
+		seen[elementID] = true
+		elementIDs = append(elementIDs, elementID)
+	}
+	for elementID := range newMap {
+		if !seen[elementID] {
+			elementIDs = append(elementIDs, elementID)
+		}
+	}
+	sort.Strings(elementIDs)
+
+	for _, elementID := range elementIDs {
+		oldValue, inOld := oldMap[elementID]
+		newValue, inNew := newMap[elementID]
+		if !inOld {
+			diff = append(diff, ElementDiff{ElementID: elementID, Added: true})
+			continue
+		}
+		if !inNew {
+			diff = append(diff, ElementDiff{ElementID: elementID, Removed: true})
+			continue
+		}
+
+		oldVal, newVal = elementValue(oldValue), elementValue(newValue)
+		if !oldVal.IsValid() && !newVal.IsValid() {
+			continue
+		}
+		if !oldVal.IsValid() {
+			oldVal = reflect.Zero(newVal.Type())
+		}
+		if !newVal.IsValid() {
+			newVal = reflect.Zero(oldVal.Type())
+		}
+		st = oldVal.Type()
+
+		// Elements of different types can only be compared as a whole, which may hold secrets
+		if newVal.Type() != st || st.Kind() != reflect.Struct {
+			if !reflect.DeepEqual(oldVal.Interface(), newVal.Interface()) {
+				diff = append(diff, ElementDiff{ElementID: elementID, Params: []ParamDiff{{
+					Before: redactValue(oldVal, true),
+					After:  redactValue(newVal, true),
+				}}})
+			}
+			continue
+		}
+
+		elementDiff := ElementDiff{ElementID: elementID}
+		for _, param := range paramPaths(st, "") {
+			paramDiff := ParamDiff{Param: param}
+			before, okBefore = fieldByPath(oldVal, param)
+			after, okAfter = fieldByPath(newVal, param)
+			if okBefore {
+				paramDiff.Before = before.Interface()
+			}
+			if okAfter {
+				paramDiff.After = after.Interface()
+			}
+			if !reflect.DeepEqual(paramDiff.Before, paramDiff.After) {
+				if isSecretParam(st, param) || okBefore && isNested(before.Type()) || okAfter && isNested(after.Type()) {
+					paramDiff.Before, paramDiff.After = redactValue(before, okBefore), redactValue(after, okAfter)
+				}
+				elementDiff.Params = append(elementDiff.Params, paramDiff)
+			}
+		}
+		if len(elementDiff.Params) > 0 {
+			diff = append(diff, elementDiff)
+		}
+	}
+	return
+}
+
+// elementValue Element v of a ResultMap, through any pointers, invalid if v or a pointer is nil
+func elementValue(v interface{}) (ev reflect.Value) {
+	ev = reflect.ValueOf(v)
+	for ev.Kind() == reflect.Ptr {
+		if ev.IsNil() {
+			return reflect.Value{}
+		}
+		ev = ev.Elem()
+	}
+	return
+}
+
+// isSecretParam Is dotted parameter path param of data object (st), or a struct holding it, a secret field, see isSecretField
+func isSecretParam(st reflect.Type, param string) bool {
+	for _, name := range strings.Split(param, ".") {
+		for st.Kind() == reflect.Ptr {
+			st = st.Elem()
+		}
+		if st.Kind() != reflect.Struct {
+			return false
+		}
+		field, ok := configFieldByName(st, name)
+		if !ok {
+			return false
+		}
+		if isSecretField(field.StructField) {
+			return true
+		}
+		st = field.Type
+	}
+	return false
+}
+
+// redactValue Value to show for secret field fv, masked unless unset
+func redactValue(fv reflect.Value, ok bool) interface{} {
+	if !ok {
+		return nil
+	}
+	if fv.IsZero() {
+		return fv.Interface()
+	}
+	return secretMask
+}
+
+// String Render the diff as an aligned table, one line per parameter changed, for review before a rollout
+// - Added and removed elements are listed without parameters
+func (diff ConfigDiff) String() string {
+	var sb strings.Builder
+
+	if len(diff) == 0 {
+		return "no changes\n"
+	}
+
+	w := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ELEMENT\tCHANGE\tPARAMETER\tBEFORE\tAFTER")
+	for _, elementDiff := range diff {
+		switch {
+		case elementDiff.Added:
+			fmt.Fprintf(w, "%s\tadded\t-\t-\t-\n", elementDiff.ElementID)
+		case elementDiff.Removed:
+			fmt.Fprintf(w, "%s\tremoved\t-\t-\t-\n", elementDiff.ElementID)
+		default:
+			for _, paramDiff := range elementDiff.Params {
+				param := paramDiff.Param
+				if len(param) == 0 {
+					param = "-"
+				}
+				fmt.Fprintf(w, "%s\tchanged\t%s\t%s\t%s\n", elementDiff.ElementID, param,
+					dumpValue(paramDiff.Before), dumpValue(paramDiff.After))
+			}
+		}
+	}
+	w.Flush()
+	return sb.String()
+}
@@ -0,0 +1,157 @@
+package goutils
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// DumpFormat How DumpConfig renders a config
+type DumpFormat int
+
+const (
+	// DumpText Aligned key = value lines, keys are dotted json tag paths, e.g. db.host
+	DumpText DumpFormat = iota
+	// DumpJSON Indented JSON, as WriteConfigFile writes
+	DumpJSON
+)
+
+// SecretNamePatterns Fields whose json tag or field name matches one of these patterns are masked by DumpConfig
+// - Patterns are matched case insensitively with filepath.Match, as well as fields tagged `secret:"true"`
+var SecretNamePatterns = []string{"*password*", "*passwd*", "*secret*", "*token*", "*apikey*", "*api_key*", "*credential*"}
+
+// DumpConfig Render a config for logging, with secret fields masked
+// - data is a config struct, a pointer to one, or the ResultMap returned by ReadConfigFiles
+// - A ResultMap is rendered element by element in Id order, as [Id] sections in text, or an object keyed by Id in JSON
+// - Secret fields that are set show as (secret), see SecretNamePatterns
+// - Values are shown as in a config file, e.g. durations as "30s"
+func DumpConfig(data interface{}, format DumpFormat) string {
+	var sb strings.Builder
+	var elementIDs []string
+	var entries []configEntry
+
+	enc := configEncoder{redact: true}
+
+	resultMap, isResultMap := data.(ResultMap)
+	if !isResultMap {
+		entries = enc.dumpEntries(data)
+		if format == DumpJSON {
+			writeJSON(&sb, entries, "")
+			return sb.String()
+		}
+		writeDumpText(&sb, entries)
+		return sb.String()
+	}
+
+	for elementID := range resultMap {
+		elementIDs = append(elementIDs, elementID)
+	}
+	sort.Strings(elementIDs)
+
+	for i, elementID := range elementIDs {
+		elementEntries := enc.dumpEntries(resultMap[elementID])
+		if format == DumpJSON {
+			entries = append(entries, configEntry{key: elementID, value: elementEntries})
+			continue
+		}
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("[" + elementID + "]\n")
+		writeDumpText(&sb, elementEntries)
+	}
+	if format == DumpJSON {
+		if entries == nil {
+			entries = []configEntry{}
+		}
+		writeJSON(&sb, entries, "")
+	}
+	return sb.String()
+}
+
+// dumpEntries Encode a config struct, or pointer to one, for DumpConfig
+func (enc configEncoder) dumpEntries(data interface{}) (entries []configEntry) {
+	sv := reflect.ValueOf(data)
+	for sv.Kind() == reflect.Ptr && !sv.IsNil() {
+		sv = sv.Elem()
+	}
+	if sv.Kind() != reflect.Struct {
+		return []configEntry{{key: "value", value: fmt.Sprintf("%v", data)}}
+	}
+
+	// The redacting encoder shows values it can't encode, rather than failing
+	entries, _ = enc.encodeStruct(sv, "")
+	if entries == nil {
+		entries = []configEntry{}
+	}
+	return
+}
+
+// isSecretField Is field tagged `secret:"true"`, or named like a secret, see SecretNamePatterns
+func isSecretField(field reflect.StructField) bool {
+	if field.Tag.Get("secret") == "true" {
+		return true
+	}
+	names := []string{strings.ToLower(field.Name)}
+	if tagName, _, _ := jsonTagName(field); tagName != "" {
+		names = append(names, strings.ToLower(tagName))
+	}
+	for _, pattern := range SecretNamePatterns {
+		for _, name := range names {
+			if matched, _ := filepath.Match(pattern, name); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeDumpText Write entries as aligned key = value lines
+func writeDumpText(sb *strings.Builder, entries []configEntry) {
+	w := tabwriter.NewWriter(sb, 0, 4, 1, ' ', 0)
+	writeDumpLines(w, "", entries)
+	w.Flush()
+}
+
+// writeDumpLines Write a line per value, nested elements and arrays of elements by dotted key path
+func writeDumpLines(w *tabwriter.Writer, prefix string, entries []configEntry) {
+	for _, entry := range entries {
+		key := prefix + entry.key
+		switch value := entry.value.(type) {
+		case []configEntry:
+			writeDumpLines(w, key+".", value)
+		case []interface{}:
+			if tomlTable(value) {
+				for i, item := range value {
+					writeDumpLines(w, fmt.Sprintf("%s[%d].", key, i), item.([]configEntry))
+				}
+				continue
+			}
+			fmt.Fprintf(w, "%s\t= %s\n", key, dumpValue(value))
+		default:
+			fmt.Fprintf(w, "%s\t= %s\n", key, dumpValue(value))
+		}
+	}
+}
+
+// dumpValue Format a value for a key = value line, strings unquoted unless empty or within an array
+func dumpValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		if v == "" {
+			return `""`
+		}
+		return v
+	case configNumber:
+		return string(v)
+	case []interface{}:
+		return tomlValue(v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,108 @@
+package goutils
+
+import (
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/AndrewDonelson/golog"
+)
+
+// envEnabled Is the environment variable overlay turned on
+func (loader *ConfigLoader) envEnabled() bool {
+	return loader.Env || len(loader.EnvPrefix) > 0
+}
+
+// overlayEnv Add environment variables for data object (st) fields as further sources of every element
+// - Each variable set becomes its own Parsed source named env:NAME, so conflicts with files are reported
+// - Field idName is skipped, so the environment can't change an element Id
+func (loader *ConfigLoader) overlayEnv(st reflect.Type, idName string, parsedMap ParsedMap) {
+	var envParsed []Parsed
+	var elementID string
+
+	if !loader.envEnabled() {
+		return
+	}
+
+	envParsed = loader.envSources(st, "", nil, idName, make(map[reflect.Type]bool))
+	if len(envParsed) == 0 {
+		return
+	}
+
+	for elementID = range parsedMap {
+		parsedMap[elementID] = append(parsedMap[elementID], envParsed...)
+	}
+	golog.Log.Debugf("Overlaying %d environment variables", len(envParsed))
+}
+
+// envSources Make a Parsed source for each environment variable set for data object (st) fields
+// - Variable name is taken from `env:"NAME"`, or EnvPrefix + json tag names joined by _ in upper case
+// - keys is the path of element keys to the nested struct st, so each source has the same shape as a file
+// - Slices are set from a comma separated list
+// - visiting holds the struct types being searched, so a self-referential struct is only searched once
+func (loader *ConfigLoader) envSources(st reflect.Type, prefix string, keys []string, idName string, visiting map[reflect.Type]bool) (envParsed []Parsed) {
+	var name, key, envValue string
+	var ok bool
+
+	visiting[st] = true
+	defer delete(visiting, st)
+
+	for _, param := range configFields(st) {
+		if len(keys) == 0 && param.Name == idName {
+			continue
+		}
+
+		key = param.key
+		paramType := param.Type
+		if paramType.Kind() == reflect.Ptr {
+			paramType = paramType.Elem()
+		}
+
+		// Nested structs are searched recursively
+		if isNested(paramType) {
+			if visiting[paramType] {
+				continue
+			}
+			nestedPrefix := ""
+			if len(loader.EnvPrefix) > 0 {
+				nestedPrefix = prefix + strings.ToUpper(key) + "_"
+			}
+			nestedKeys := append(append([]string{}, keys...), key)
+			envParsed = append(envParsed, loader.envSources(paramType, nestedPrefix, nestedKeys, idName, visiting)...)
+			continue
+		}
+
+		name, ok = param.Tag.Lookup("env")
+		if !ok {
+			if len(loader.EnvPrefix) == 0 {
+				continue
+			}
+			name = loader.EnvPrefix + prefix + strings.ToUpper(key)
+		}
+		envValue, ok = os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		// Build element map with the same nesting as a config file
+		var v interface{} = envValue
+		if paramType.Kind() == reflect.Slice {
+			var arr []interface{}
+			for _, item := range strings.Split(envValue, ",") {
+				arr = append(arr, strings.TrimSpace(item))
+			}
+			v = arr
+		}
+		elementMap := ElementMap{key: v}
+		for j := len(keys) - 1; j >= 0; j-- {
+			elementMap = ElementMap{keys[j]: map[string]interface{}(elementMap)}
+		}
+
+		envParsed = append(envParsed, Parsed{
+			FileName:     "env:" + name,
+			DistinctName: "env:" + name,
+			ElementMap:   elementMap,
+		})
+	}
+	return
+}
@@ -0,0 +1,109 @@
+package goutils
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Errors returned by the config loaders, test for them with errors.Is
+var (
+	// ErrInvalidTarget The 'data' given to a config loader is not a non-nil pointer to a struct
+	ErrInvalidTarget = errors.New("'data' must be a non-nil pointer to a struct")
+
+	// ErrUnknownIDField The idName given to ReadConfigFiles is not a field of the struct 'data' points to
+	ErrUnknownIDField = errors.New("id field not found")
+
+	// ErrElementNotObject A config file, or an item of a config file's array, is not an element of key-values
+	// - Returned as a *ConfigElementError, giving the file and position of the element
+	ErrElementNotObject = errors.New("config element is not an object")
+)
+
+// ConfigElementError A config element that could not be read, test for it with errors.As
+// - Position is the element # within the file's array, from 1, or 0 if the file is a single element
+// - Type is the kind of value found instead, e.g. string or slice
+type ConfigElementError struct {
+	File     string
+	Position int
+	Type     string
+	Err      error
+}
+
+// Error Describe the element, and where it was found
+func (e *ConfigElementError) Error() string {
+	location := e.File
+	if e.Position > 0 {
+		location = fmt.Sprintf("%s:elem#%d", e.File, e.Position)
+	}
+	return fmt.Sprintf("%v, found %s [%s]", e.Err, e.Type, location)
+}
+
+// Unwrap The underlying error, such as ErrElementNotObject
+func (e *ConfigElementError) Unwrap() error {
+	return e.Err
+}
+
+// valueKind Kind of decoded config value v for error messages, null if nil
+func valueKind(v interface{}) string {
+	if v == nil {
+		return "null"
+	}
+	return reflect.TypeOf(v).Kind().String()
+}
+
+// configTarget Check 'data' given to caller is a non-nil pointer to a struct, returning the struct's type and value
+func configTarget(caller string, data interface{}) (st reflect.Type, sv reflect.Value, err error) {
+	t := reflect.TypeOf(data)
+	switch {
+	case t == nil:
+		err = fmt.Errorf("%s: %w, not nil", caller, ErrInvalidTarget)
+	case t.Kind() != reflect.Ptr:
+		err = fmt.Errorf("%s: %w, not %s", caller, ErrInvalidTarget, t)
+	case reflect.ValueOf(data).IsNil():
+		err = fmt.Errorf("%s: %w, not nil %s", caller, ErrInvalidTarget, t)
+	case t.Elem().Kind() != reflect.Struct:
+		err = fmt.Errorf("%s: %w, not %s", caller, ErrInvalidTarget, t)
+	default:
+		return t.Elem(), reflect.ValueOf(data).Elem(), nil
+	}
+	return
+}
+
+// configErrors An ErrList compiled into an error, keeping the typed errors it lists for errors.Is and errors.As
+type configErrors struct {
+	err  error
+	errs []error
+}
+
+// withErrors Attach typed errs, already listed in err's message, to err
+func withErrors(err error, errs []error) error {
+	if err == nil || len(errs) == 0 {
+		return err
+	}
+	return &configErrors{err: err, errs: errs}
+}
+
+// Error The compiled error message
+func (e *configErrors) Error() string {
+	return e.err.Error()
+}
+
+// Is Does any listed error match target
+func (e *configErrors) Is(target error) bool {
+	for _, err := range e.errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As Find the first listed error matching target
+func (e *configErrors) As(target interface{}) bool {
+	for _, err := range e.errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
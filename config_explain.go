@@ -0,0 +1,197 @@
+package goutils
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// ConfigExplanation Where the final value of each element parameter came from, see Explain
+type ConfigExplanation []ExplainedParam
+
+// ExplainedParam The final value of one element parameter, with its source and any values it overrode
+// - Source is as reported in ProvenanceMap, empty if no source or default gave the parameter a value
+type ExplainedParam struct {
+	ElementID  string
+	Param      string
+	Value      interface{}
+	Source     string
+	Overridden []ExplainedCandidate
+}
+
+// ExplainedCandidate A value given for a parameter by a source that didn't win
+type ExplainedCandidate struct {
+	Value  string
+	Source string
+}
+
+// Explain Read a list of config files as ReadConfigFiles, explaining where each element parameter's value came from
+// - Parameters are listed by element Id, then in struct field order, nested struct parameters by dotted path
+// - The explanation is returned with any error, so a config that fails to load can still be explained
+// - Decrypted values, and values of secret fields, show as (secret), see SecretNamePatterns
+func (loader *ConfigLoader) Explain(data interface{}, idName string, filenames ...string) (explanation ConfigExplanation, err error) {
+	var resultMap ResultMap
+	var provenanceMap ProvenanceMap
+	var parsedMap ParsedMap
+	var secretMap map[string]map[string]bool
+	var elementIDs, params []string
+	var source string
+	var secret bool
+
+	resultMap, provenanceMap, parsedMap, secretMap, err = loader.readConfigFiles(data, idName, filenames...)
+	if errors.Is(err, ErrInvalidTarget) || errors.Is(err, ErrUnknownIDField) {
+		return
+	}
+
+	st := reflect.TypeOf(data).Elem()
+	params = paramPaths(st, "")
+
+	for elementID := range resultMap {
+		elementIDs = append(elementIDs, elementID)
+	}
+	sort.Strings(elementIDs)
+
+	for _, elementID := range elementIDs {
+		candidates := loader.explainCandidates(st, parsedMap[elementID])
+		ev := reflect.ValueOf(resultMap[elementID])
+
+		for _, param := range params {
+			source = provenanceMap[elementID][param]
+			explained := ExplainedParam{
+				ElementID: elementID,
+				Param:     param,
+				Source:    source,
+			}
+			secret = isDecryptedParam(secretMap[elementID], param) || isSecretParam(st, param)
+			if fv, ok := fieldByPath(ev, param); ok {
+				explained.Value = fv.Interface()
+
+				// A nested struct listed as one parameter may hold secrets
+				if secret || isNested(fv.Type()) {
+					explained.Value = redactValue(fv, ok)
+				}
+			}
+
+			// Any value from a source that isn't the winner was overridden
+			winners := strings.Split(source, ",")
+			for _, candidate := range candidates[param] {
+				if !StringArrayContains(winners, candidate.Source) {
+					if secret {
+						candidate.Value = secretMask
+					}
+					explained.Overridden = append(explained.Overridden, candidate)
+				}
+			}
+			explanation = append(explanation, explained)
+		}
+	}
+	return
+}
+
+// String Render the explanation as an aligned table, for an --explain-config mode
+func (explanation ConfigExplanation) String() string {
+	var sb strings.Builder
+	var source string
+
+	w := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ELEMENT\tPARAMETER\tVALUE\tSOURCE\tOVERRIDDEN")
+	for _, explained := range explanation {
+		source = explained.Source
+		if len(source) == 0 {
+			source = "-"
+		}
+		var overridden []string
+		for _, candidate := range explained.Overridden {
+			overridden = append(overridden, fmt.Sprintf("%q [%s]", candidate.Value, candidate.Source))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%v\t%s\t%s\n", explained.ElementID, explained.Param, explained.Value,
+			source, strings.Join(overridden, ", "))
+	}
+	w.Flush()
+	return sb.String()
+}
+
+// explainCandidates List every value given for each parameter by an element's sources, in source order
+func (loader *ConfigLoader) explainCandidates(st reflect.Type, parsedArr []Parsed) (candidates map[string][]ExplainedCandidate) {
+	candidates = make(map[string][]ExplainedCandidate)
+	for _, parsed := range parsedArr {
+		params := make(map[string]interface{})
+		loader.flattenParams(st, "", parsed.ElementMap, params, make(map[string]bool))
+		for param, v := range params {
+			candidates[param] = append(candidates[param], ExplainedCandidate{
+				Value:  fmt.Sprintf("%v", v),
+				Source: parsed.location(),
+			})
+		}
+	}
+	return
+}
+
+// isDecryptedParam Was dotted parameter path param, or an item of it, given a decrypted value, see parseContext.secrets
+func isDecryptedParam(secrets map[string]bool, param string) bool {
+	for secretParam := range secrets {
+		if secretParam == param || strings.HasPrefix(secretParam, param+".") || strings.HasPrefix(secretParam, param+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// paramPaths List the parameter paths of data object (st) fields in struct order, nested struct parameters by dotted path
+// - A struct nested within a struct of the same type, e.g. Fallback *Config, is listed as a single parameter
+func paramPaths(st reflect.Type, prefix string) (params []string) {
+	return typeParamPaths(st, prefix, make(map[reflect.Type]bool))
+}
+
+// typeParamPaths List parameter paths as paramPaths, where visiting holds the struct types being listed
+func typeParamPaths(st reflect.Type, prefix string, visiting map[reflect.Type]bool) (params []string) {
+	visiting[st] = true
+	defer delete(visiting, st)
+
+	for _, field := range configFields(st) {
+		if isNested(field.Type) {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			if !visiting[fieldType] {
+				params = append(params, typeParamPaths(fieldType, prefix+field.Name+".", visiting)...)
+				continue
+			}
+		}
+		params = append(params, prefix+field.Name)
+	}
+	return
+}
+
+// fieldByPath Find the field of struct value v named by dotted parameter path, through any pointers
+// - A non-nil pointer field is returned as the value it points to
+func fieldByPath(v reflect.Value, param string) (fv reflect.Value, ok bool) {
+	fv = v
+	for _, name := range strings.Split(param, ".") {
+		for fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				return
+			}
+			fv = fv.Elem()
+		}
+		if fv.Kind() != reflect.Struct {
+			return
+		}
+		field, found := configFieldByName(fv.Type(), name)
+		if !found {
+			return
+		}
+		if fv, found = fieldValue(fv, field, false); !found {
+			return
+		}
+	}
+	for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+		fv = fv.Elem()
+	}
+	ok = true
+	return
+}
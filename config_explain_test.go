@@ -0,0 +1,60 @@
+package goutils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfigLoaderExplain(t *testing.T) {
+	var config testNestedConfig
+	files := []string{"test/config/layers/base.json", "test/config/layers/prod.yaml", "test/config/layers/local.toml"}
+
+	loader := &ConfigLoader{Merge: MergeLastWins}
+	explanation, err := loader.Explain(&config, "Name", files...)
+	Ok(t, err)
+	Equals(t, 8, len(explanation))
+
+	retries := explanation[5]
+	Equals(t, "Retries", retries.Param)
+	Equals(t, 3, retries.Value)
+	Equals(t, "local.toml", retries.Source)
+	Equals(t, []ExplainedCandidate{{Value: "1", Source: "base.json"}, {Value: "2", Source: "prod.yaml"}}, retries.Overridden)
+
+	port := explanation[2]
+	Equals(t, "DB.Port", port.Param)
+	Equals(t, "base.json", port.Source)
+	Equals(t, 0, len(port.Overridden))
+
+	table := strings.Split(explanation.String(), "\n")
+	Equals(t, 10, len(table))
+	Equals(t, []string{"ELEMENT", "PARAMETER", "VALUE", "SOURCE", "OVERRIDDEN"}, strings.Fields(table[0]))
+	Equals(t, []string{"alpha", "DB.Host", "db.prod", "prod.yaml", `"db.base"`, "[base.json]"}, strings.Fields(table[2]))
+	Equals(t, []string{"alpha", "Backup.Host", "<nil>", "-"}, strings.Fields(table[7]))
+}
+
+func TestConfigLoaderExplainSecrets(t *testing.T) {
+	var config testSecretConfig
+
+	loader := &ConfigLoader{Keys: FileKeyProvider{Path: "test/config/secret.key"}}
+	explanation, err := loader.Explain(&config, "Name", "test/config/secrets.yaml")
+	Assert(t, err != nil, "expected DB.Port error")
+
+	// decrypted values, and values interpolated from them, are masked
+	table := explanation.String()
+	Assert(t, !strings.Contains(table, "hunter2") && !strings.Contains(table, "db.secret"), table)
+	Equals(t, "Password", explanation[1].Param)
+	Equals(t, secretMask, explanation[1].Value)
+	Equals(t, "DSN", explanation[2].Param)
+	Equals(t, secretMask, explanation[2].Value)
+	Equals(t, "DB.Host", explanation[3].Param)
+	Equals(t, secretMask, explanation[3].Value)
+}
+
+func TestConfigLoaderExplainSelfReferential(t *testing.T) {
+	var config testSelfConfig
+
+	explanation, err := (&ConfigLoader{}).Explain(&config, "Name", "test/config/single.yaml")
+	Assert(t, err != nil, "expected unused setting errors")
+	Equals(t, 3, len(explanation))
+	Equals(t, "Fallback", explanation[2].Param)
+}
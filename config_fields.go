@@ -0,0 +1,169 @@
+package goutils
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// configField A data object field read from config files
+// - key is the field's name in config files, its json tag name, or field name if untagged
+// - Index is the path of field indexes from the data object, more than one for fields promoted from embedded structs
+// - omitEmpty is set by the json tag's omitempty option, so WriteConfigFile leaves out empty values
+type configField struct {
+	reflect.StructField
+	key       string
+	tagged    bool
+	omitEmpty bool
+}
+
+// jsonTagName Parse the `json:"..."` tag of field, ignoring options such as omitempty
+// - skip is true for `json:"-"`, while `json:"-,"` names the key -
+func jsonTagName(field reflect.StructField) (name string, omitEmpty, skip bool) {
+	tag, ok := field.Tag.Lookup("json")
+	if !ok {
+		return "", false, false
+	}
+	if tag == "-" {
+		return "", false, true
+	}
+	name = tag
+	if i := strings.Index(tag, ","); i >= 0 {
+		name = tag[:i]
+		for _, option := range strings.Split(tag[i+1:], ",") {
+			if option == "omitempty" {
+				omitEmpty = true
+			}
+		}
+	}
+	return name, omitEmpty, false
+}
+
+// configFields List the fields of data object (st) read from config files, in struct order
+// - Unexported fields, and fields tagged `json:"-"`, are skipped
+// - Fields of embedded structs without a json tag name are promoted, as encoding/json does
+// - A promoted field is hidden by a field with the same key nearer the data object, at the same depth by a tagged field
+// - Fields with the same key, depth and tagging are ambiguous, so neither is read
+func configFields(st reflect.Type) (fields []configField) {
+	var visible []configField
+	var name string
+	var i int
+	var omitEmpty, skip bool
+
+	for i = 0; i < st.NumField(); i++ {
+		field := st.Field(i)
+		name, omitEmpty, skip = jsonTagName(field)
+		if skip {
+			continue
+		}
+
+		// Promote the fields of embedded structs
+		embedded := field.Type
+		if embedded.Kind() == reflect.Ptr {
+			embedded = embedded.Elem()
+		}
+		if field.Anonymous && name == "" && isNested(embedded) {
+			if field.PkgPath != "" && field.Type.Kind() == reflect.Ptr {
+				continue
+			}
+			for _, promoted := range configFields(embedded) {
+				promoted.Index = append([]int{i}, promoted.Index...)
+				fields = append(fields, promoted)
+			}
+			continue
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+		field.Index = []int{i}
+		if name == "" {
+			fields = append(fields, configField{StructField: field, key: field.Name, omitEmpty: omitEmpty})
+		} else {
+			fields = append(fields, configField{StructField: field, key: name, tagged: true, omitEmpty: omitEmpty})
+		}
+	}
+
+	// Keep the dominant field for each key
+	for i, field := range fields {
+		dominant := true
+		for j, other := range fields {
+			if j == i || other.key != field.key {
+				continue
+			}
+			if len(other.Index) < len(field.Index) ||
+				len(other.Index) == len(field.Index) && (other.tagged || !field.tagged) {
+				dominant = false
+				break
+			}
+		}
+		if dominant {
+			visible = append(visible, field)
+		}
+	}
+	return visible
+}
+
+// configFieldByName Find the field of data object (st) with parameter name, see configFields
+func configFieldByName(st reflect.Type, name string) (field configField, ok bool) {
+	for _, field = range configFields(st) {
+		if field.Name == name {
+			return field, true
+		}
+	}
+	return configField{}, false
+}
+
+// fieldValue Field of struct value sv for field, through any embedded struct pointers
+// - If alloc, nil embedded pointers are allocated, otherwise ok is false if one is nil
+func fieldValue(sv reflect.Value, field configField, alloc bool) (fv reflect.Value, ok bool) {
+	fv = sv
+	for i, index := range field.Index {
+		if i > 0 && fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				if !alloc {
+					return fv, false
+				}
+				fv.Set(reflect.New(fv.Type().Elem()))
+			}
+			fv = fv.Elem()
+		}
+		fv = fv.Field(index)
+	}
+	return fv, true
+}
+
+// lookupParam Find the value for field in element map m, by json tag name first, then by field name
+// - With CaseInsensitiveKeys, keys differing only in case also match, if no key matches exactly
+func (loader *ConfigLoader) lookupParam(field configField, m map[string]interface{}) (v interface{}, ok bool) {
+	if v, ok = m[field.key]; ok {
+		return
+	}
+	if v, ok = m[field.Name]; ok {
+		return
+	}
+	if !loader.CaseInsensitiveKeys {
+		return
+	}
+
+	// Sort keys, so the same key is found each time if several differ only in case
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if strings.EqualFold(key, field.key) || strings.EqualFold(key, field.Name) {
+			return m[key], true
+		}
+	}
+	return nil, false
+}
+
+// matchesField Is element key a name of field, see lookupParam
+func (loader *ConfigLoader) matchesField(key string, field configField) bool {
+	if key == field.key || key == field.Name {
+		return true
+	}
+	return loader.CaseInsensitiveKeys && (strings.EqualFold(key, field.key) || strings.EqualFold(key, field.Name))
+}
@@ -0,0 +1,149 @@
+package goutils
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+
+	"github.com/AndrewDonelson/golog"
+)
+
+// configFlag flag.Value for a config struct field, holding the raw string until the config is read
+// - keys is the path of element keys to the field, e.g. ["db", "host"] for flag -db.host
+type configFlag struct {
+	keys   []string
+	param  string
+	value  interface{}
+	isBool bool
+	slice  bool
+}
+
+// String Raw value given on the command line
+func (cf *configFlag) String() string {
+	if cf == nil || cf.value == nil {
+		return ""
+	}
+	if arr, ok := cf.value.([]interface{}); ok {
+		var items []string
+		for _, item := range arr {
+			items = append(items, item.(string))
+		}
+		return strings.Join(items, ",")
+	}
+	return cf.value.(string)
+}
+
+// Set Store raw value, slice flags may be repeated or given a comma separated list
+func (cf *configFlag) Set(s string) error {
+	if !cf.slice {
+		cf.value = s
+		return nil
+	}
+	arr, _ := cf.value.([]interface{})
+	for _, item := range strings.Split(s, ",") {
+		arr = append(arr, strings.TrimSpace(item))
+	}
+	cf.value = arr
+	return nil
+}
+
+// IsBoolFlag Allow -debug as well as -debug=true for bool fields
+func (cf *configFlag) IsBoolFlag() bool {
+	return cf.isBool
+}
+
+// NewConfigFlagSet Make a flag.FlagSet with a flag for each field of the struct 'data' points to
+// - Flags are named by json tag, nested struct fields are joined by a dot, e.g. -db.host
+// - Help text is taken from the `usage:"..."` tag
+// - Set ConfigLoader.Flags to the parsed FlagSet to apply flags over config files
+// - Values are converted when the config is read, by the same rules as config files
+func NewConfigFlagSet(data interface{}, name string, errorHandling flag.ErrorHandling) *flag.FlagSet {
+	flagSet := flag.NewFlagSet(name, errorHandling)
+
+	st := reflect.TypeOf(data)
+	if st != nil && st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if st != nil && st.Kind() == reflect.Struct {
+		addConfigFlags(flagSet, st, nil, "", make(map[reflect.Type]bool))
+	}
+	return flagSet
+}
+
+// addConfigFlags Add a flag for each field of data object (st), where keys and param are the path to the nested struct
+// - visiting holds the struct types being added, so a self-referential struct only gets flags once
+func addConfigFlags(flagSet *flag.FlagSet, st reflect.Type, keys []string, param string, visiting map[reflect.Type]bool) {
+	var usage string
+
+	visiting[st] = true
+	defer delete(visiting, st)
+
+	for _, field := range configFields(st) {
+		fieldKeys := append(append([]string{}, keys...), field.key)
+		fieldParam := param + field.Name
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+
+		// Nested structs get a flag for each of their fields
+		if isNested(fieldType) {
+			if !visiting[fieldType] {
+				addConfigFlags(flagSet, fieldType, fieldKeys, fieldParam+".", visiting)
+			}
+			continue
+		}
+		if fieldType.Kind() == reflect.Map {
+			continue
+		}
+
+		usage = field.Tag.Get("usage")
+		flagSet.Var(&configFlag{
+			keys:   fieldKeys,
+			param:  fieldParam,
+			isBool: fieldType.Kind() == reflect.Bool,
+			slice:  fieldType.Kind() == reflect.Slice,
+		}, strings.Join(fieldKeys, "."), usage)
+	}
+}
+
+// overlayFlags Add each flag set on the command line as the last source of every element
+// - Flags are Override sources, so take precedence over files and environment whatever the MergePolicy
+// - Field idName is skipped, so flags can't change an element Id
+func (loader *ConfigLoader) overlayFlags(idName string, parsedMap ParsedMap) {
+	var flagParsed []Parsed
+	var elementID string
+
+	if loader.Flags == nil {
+		return
+	}
+
+	loader.Flags.Visit(func(f *flag.Flag) {
+		cf, ok := f.Value.(*configFlag)
+		if !ok || cf.param == idName {
+			return
+		}
+
+		// Build element map with the same nesting as a config file
+		elementMap := ElementMap{cf.keys[len(cf.keys)-1]: cf.value}
+		for j := len(cf.keys) - 2; j >= 0; j-- {
+			elementMap = ElementMap{cf.keys[j]: map[string]interface{}(elementMap)}
+		}
+
+		flagParsed = append(flagParsed, Parsed{
+			FileName:     "flag:" + f.Name,
+			DistinctName: "flag:" + f.Name,
+			ElementMap:   elementMap,
+			Override:     true,
+		})
+	})
+	if len(flagParsed) == 0 {
+		return
+	}
+
+	for elementID = range parsedMap {
+		parsedMap[elementID] = append(parsedMap[elementID], flagParsed...)
+	}
+	golog.Log.Debugf("Overlaying %d command-line flags", len(flagParsed))
+}
@@ -0,0 +1,148 @@
+package goutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Config file formats, chosen by file extension
+const (
+	ConfigFormatJSON = "json"
+	ConfigFormatYAML = "yaml"
+	ConfigFormatTOML = "toml"
+)
+
+// ConfigFormat Return the config format for filename based on its extension
+// - .yaml and .yml are YAML, .toml is TOML, anything else is read as JSON
+func ConfigFormat(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return ConfigFormatYAML
+	case ".toml":
+		return ConfigFormatTOML
+	default:
+		return ConfigFormatJSON
+	}
+}
+
+// decodeConfig Parse config file contents into map[string]interface{}, or a slice of these
+// - Decoder is chosen from the filename extension
+// - YAML and TOML results are normalized so they look the same as decoded JSON
+// - TOML has no top-level arrays, so an array of tables is left for tomlElements to unwrap
+// - positions holds the line and column of each key, and errors have the position of the problem, see KeyPositions
+func decodeConfig(filename string, b []byte) (config interface{}, positions KeyPositions, err error) {
+	format := ConfigFormat(filename)
+	switch format {
+	case ConfigFormatYAML:
+		var node yaml.Node
+		if err = yaml.Unmarshal(b, &node); err != nil {
+			return nil, nil, syntaxError(format, b, err)
+		}
+		if len(node.Content) > 0 {
+			keepTimestamps(&node)
+			if err = node.Decode(&config); err != nil {
+				return nil, nil, syntaxError(format, b, err)
+			}
+		}
+		config = normalizeConfig(config)
+		positions = make(KeyPositions)
+		yamlPositions(&node, "", positions)
+	case ConfigFormatTOML:
+		var tomlMap map[string]interface{}
+		_, err = toml.Decode(string(b), &tomlMap)
+		if err != nil {
+			return nil, nil, syntaxError(format, b, err)
+		}
+		config = normalizeConfig(tomlMap)
+		positions = tomlPositions(b)
+	default:
+		if err = json.Unmarshal(b, &config); err != nil {
+			return nil, nil, syntaxError(format, b, err)
+		}
+		positions = jsonPositions(b)
+	}
+	return
+}
+
+// tomlElements Read a TOML config holding nothing but one array of tables, e.g. [[servers]], as an array of elements
+// - Unless data object (st) has a field for the array's key, e.g. Servers []Server, so it is a single element
+// - Configs in other formats, or with other keys, are returned as they are
+func (loader *ConfigLoader) tomlElements(filename string, config interface{}, positions KeyPositions, st reflect.Type) (interface{}, KeyPositions) {
+	element, isMap := config.(map[string]interface{})
+	if ConfigFormat(filename) != ConfigFormatTOML || !isMap || len(element) != 1 {
+		return config, positions
+	}
+	for key, v := range element {
+		elements, isArr := v.([]interface{})
+		if !isArr || len(elements) == 0 {
+			return config, positions
+		}
+		for _, item := range elements {
+			if _, isTable := item.(map[string]interface{}); !isTable {
+				return config, positions
+			}
+		}
+		for _, field := range configFields(st) {
+			if loader.matchesField(key, field) {
+				return config, positions
+			}
+		}
+		return elements, positions.sub(key)
+	}
+	return config, positions
+}
+
+// keepTimestamps Decode YAML timestamps as the strings they are written as
+// - So times are read by the same layouts as JSON, and times without a zone get ConfigLoader.Location
+func keepTimestamps(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode && node.ShortTag() == "!!timestamp" {
+		node.Tag = "!!str"
+	}
+	for _, child := range node.Content {
+		keepTimestamps(child)
+	}
+}
+
+// normalizeConfig Convert decoded YAML/TOML values into the types json.Unmarshal produces
+// - Maps get string keys, typed slices become []interface{}
+// - Timestamps become strings, so are parsed by the same layouts as JSON dates, keeping fractional seconds and offset
+func normalizeConfig(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			value[key] = normalizeConfig(item)
+		}
+		return value
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			m[fmt.Sprintf("%v", key)] = normalizeConfig(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range value {
+			value[i] = normalizeConfig(item)
+		}
+		return value
+	case []map[string]interface{}:
+		arr := make([]interface{}, len(value))
+		for i, item := range value {
+			arr[i] = normalizeConfig(item)
+		}
+		return arr
+	case time.Time:
+		if value.Location() == time.UTC && value.Equal(value.Truncate(24*time.Hour)) {
+			return value.Format("2006-01-02")
+		}
+		return value.Format(time.RFC3339Nano)
+	default:
+		return v
+	}
+}
@@ -0,0 +1,191 @@
+package goutils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ConfigIncludeKey Config element key listing other files to read, e.g. "$include": ["common/*.json"]
+// - Paths are glob patterns, relative to the directory of the file including them
+const ConfigIncludeKey = "$include"
+
+// ReadConfigPaths Read config files as ReadConfigFiles, where each path may be a file, directory or glob pattern
+func ReadConfigPaths(data interface{}, idName string, paths ...string) (resultMap ResultMap, err error) {
+	return (&ConfigLoader{}).ReadConfigPaths(data, idName, paths...)
+}
+
+// ReadConfigPaths Read config files from paths using loader options, see ReadConfigPaths
+// - Directories are read conf.d style: every .json, .yaml, .yml and .toml file in them, in name order
+// - Glob patterns are expanded in name order, a pattern matching nothing is reported as a missing file
+func (loader *ConfigLoader) ReadConfigPaths(data interface{}, idName string, paths ...string) (resultMap ResultMap, err error) {
+	return loader.ReadConfigFiles(data, idName, ExpandConfigPaths(paths...)...)
+}
+
+// ExpandConfigPaths List config files named by paths, expanding directories and glob patterns
+// - Paths that are neither are returned as given, so ReadConfigFiles can report them
+func ExpandConfigPaths(paths ...string) (filenames []string) {
+	var matches []string
+	var err error
+
+	for _, path := range paths {
+		if fileInfo, statErr := os.Stat(path); statErr == nil && fileInfo.IsDir() {
+			filenames = append(filenames, configFilesInDir(path)...)
+			continue
+		}
+		if strings.ContainsAny(path, "*?[") {
+			matches, err = filepath.Glob(path)
+			if err == nil && len(matches) > 0 {
+				sort.Strings(matches)
+				filenames = append(filenames, matches...)
+				continue
+			}
+		}
+		filenames = append(filenames, path)
+	}
+	return
+}
+
+// configFilesInDir List files in dir with a config file extension, in name order
+func configFilesInDir(dir string) (filenames []string) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return []string{dir}
+	}
+	for _, file := range files {
+		switch strings.ToLower(filepath.Ext(file.Name())) {
+		case ".json", ".yaml", ".yml", ".toml":
+			if !file.IsDir() {
+				filenames = append(filenames, filepath.Join(dir, file.Name()))
+			}
+		}
+	}
+	return
+}
+
+// includedFiles List filenames with the files each includes ahead of it, as ReadConfigFiles reads them, see newIncluder
+// - errList reports problems with $include directives, such as cycles and patterns matching no files
+func includedFiles(filenames ...string) (expanded []string, errList ErrList) {
+	inc := newIncluder(&errList)
+	for _, filename := range filenames {
+		inc.include(filename, nil)
+	}
+	return inc.expanded, errList
+}
+
+// decodedConfig Contents of a config file already read, with the position of each key
+// - includes is set if the file had a $include directive, which has been removed from config
+type decodedConfig struct {
+	config    interface{}
+	positions KeyPositions
+	includes  bool
+}
+
+// includer State for expanding $include directives across a list of config files
+// - decoded holds each file already read, so it is only parsed once
+// - listed holds the full path of files already in the expanded list
+type includer struct {
+	expanded []string
+	decoded  map[string]decodedConfig
+	listed   map[string]bool
+	errList  *ErrList
+}
+
+// newIncluder Start expanding $include directives, files are listed with the files each includes ahead of it
+// - So an including file overrides its includes
+// - Each included file is listed once, however many files include it
+// - Cycles are reported and skipped
+// - Files that couldn't be read or parsed are listed without contents, so the caller reports them
+func newIncluder(errList *ErrList) *includer {
+	return &includer{
+		decoded: make(map[string]decodedConfig),
+		listed:  make(map[string]bool),
+		errList: errList,
+	}
+}
+
+// include Read filename and the files it includes, where stack lists the full paths of files including it
+func (inc *includer) include(filename string, stack []string) {
+	var fullpath, dir, pattern string
+	var patterns, matches []string
+	var config interface{}
+	var positions KeyPositions
+	var b []byte
+	var includes bool
+	var err error
+
+	fullpath, err = filepath.Abs(filename)
+	if err != nil {
+		inc.expanded = append(inc.expanded, filename)
+		return
+	}
+	for i, including := range stack {
+		if including == fullpath {
+			inc.errList.Addf("include cycle %s, skipping [%s]",
+				strings.Join(append(stack[i:], fullpath), " -> "), filename)
+			return
+		}
+	}
+	if len(stack) > 0 && inc.listed[fullpath] {
+		return
+	}
+
+	if b, err = ioutil.ReadFile(filename); err != nil {
+		inc.expanded = append(inc.expanded, filename)
+		return
+	}
+	if config, positions, err = decodeConfig(filename, b); err != nil {
+		inc.expanded = append(inc.expanded, filename)
+		return
+	}
+
+	element, isMap := config.(map[string]interface{})
+	if isMap {
+		if v, ok := element[ConfigIncludeKey]; ok {
+			includes = true
+			delete(element, ConfigIncludeKey)
+			switch value := v.(type) {
+			case string:
+				patterns = []string{value}
+			case []interface{}:
+				for _, item := range value {
+					if s, isString := item.(string); isString {
+						patterns = append(patterns, s)
+					} else {
+						inc.errList.Addf("invalid %s %v, skipping [%s]", ConfigIncludeKey, item, filename)
+					}
+				}
+			default:
+				inc.errList.Addf("invalid %s %v, skipping [%s]", ConfigIncludeKey, v, filename)
+			}
+
+			// Included files go first, resolved relative to this file
+			dir = filepath.Dir(filename)
+			for _, pattern = range patterns {
+				if !filepath.IsAbs(pattern) {
+					pattern = filepath.Join(dir, pattern)
+				}
+				matches, err = filepath.Glob(pattern)
+				if err != nil || len(matches) == 0 {
+					inc.errList.Addf("include %s matches no files [%s]", pattern, filename)
+					continue
+				}
+				sort.Strings(matches)
+				for _, match := range matches {
+					inc.include(match, append(append([]string{}, stack...), fullpath))
+				}
+			}
+
+			// A file holding nothing but includes has no element of its own
+			if len(element) == 0 {
+				config = []interface{}{}
+			}
+		}
+	}
+
+	inc.listed[fullpath] = true
+	inc.expanded = append(inc.expanded, filename)
+	inc.decoded[filename] = decodedConfig{config: config, positions: positions, includes: includes}
+}
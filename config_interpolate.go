@@ -0,0 +1,177 @@
+package goutils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Interpolation reference prefixes, e.g. ${env:HOME} or ${file:/run/secrets/db_password}
+// - Any other reference names a parameter of the same element, by json tag path or dotted parameter path, e.g. ${db.host}
+// - $${ gives a literal ${
+const (
+	InterpolateEnvPrefix  = "env:"
+	InterpolateFilePrefix = "file:"
+)
+
+// interpolationVars Collect the raw value of each parameter of an element, for ${...} references
+// - Values are taken from sources in order by the same precedence as parseValue, then from `default:"..."` tags
+// - Each value is keyed by both its dotted parameter path and its json tag path, e.g. DB.Host and db.host
+func (loader *ConfigLoader) interpolationVars(st reflect.Type, parsedArr []Parsed) (vars map[string]interface{}) {
+	vars = make(map[string]interface{})
+	for _, parsed := range parsedArr {
+		keep := loader.Merge == MergeFirstWins && !parsed.Override
+		loader.addInterpolationVars(st, "", "", parsed.ElementMap, vars, keep)
+	}
+	addDefaultVars(st, "", "", vars, make(map[reflect.Type]bool))
+	return
+}
+
+// addInterpolationVars Add element map m values for data object (st) fields to vars, see interpolationVars
+// - If keep, values already in vars are not replaced
+func (loader *ConfigLoader) addInterpolationVars(st reflect.Type, prefix, tagPrefix string, m map[string]interface{}, vars map[string]interface{}, keep bool) {
+	var v interface{}
+	var ok bool
+
+	for _, field := range configFields(st) {
+		v, ok = loader.lookupParam(field, m)
+		if !ok {
+			continue
+		}
+		tagName := field.key
+
+		nested, isMap := v.(map[string]interface{})
+		nestedType := field.Type
+		if nestedType.Kind() == reflect.Ptr {
+			nestedType = nestedType.Elem()
+		}
+		if isMap && isNested(nestedType) {
+			loader.addInterpolationVars(nestedType, prefix+field.Name+".", tagPrefix+tagName+".", nested, vars, keep)
+			continue
+		}
+		if _, found := vars[prefix+field.Name]; found && keep {
+			continue
+		}
+		vars[prefix+field.Name] = v
+		vars[tagPrefix+tagName] = v
+	}
+}
+
+// addDefaultVars Add `default:"..."` tag values for data object (st) fields missing from vars
+// - visiting holds the struct types being searched, so a self-referential struct is only searched once
+func addDefaultVars(st reflect.Type, prefix, tagPrefix string, vars map[string]interface{}, visiting map[reflect.Type]bool) {
+	visiting[st] = true
+	defer delete(visiting, st)
+
+	for _, field := range configFields(st) {
+		tagName := field.key
+		if isNested(field.Type) {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			if !visiting[fieldType] {
+				addDefaultVars(fieldType, prefix+field.Name+".", tagPrefix+tagName+".", vars, visiting)
+			}
+			continue
+		}
+		value, ok := field.Tag.Lookup("default")
+		if _, found := vars[prefix+field.Name]; ok && !found {
+			vars[prefix+field.Name] = value
+			vars[tagPrefix+tagName] = value
+		}
+	}
+}
+
+// interpolate Replace each ${...} reference in config value s, for parameter param
+// - stack lists the parameters being resolved, to detect reference cycles
+// - Errors are reported against the current source, and ok is false
+func (ctx *parseContext) interpolate(s, param string, stack []string) (result string, ok bool) {
+	var sb strings.Builder
+	var ref, value string
+	var start, end int
+
+	for {
+		start = strings.Index(s, "${")
+		if start < 0 {
+			sb.WriteString(s)
+			return sb.String(), true
+		}
+		if start > 0 && s[start-1] == '$' {
+			sb.WriteString(s[:start-1] + "${")
+			s = s[start+2:]
+			continue
+		}
+		end = strings.Index(s[start:], "}")
+		if end < 0 {
+			ctx.errList.Addf("setting for %s invalid, parameter %s: unterminated reference %s [%s]",
+				ctx.elementID, param, s[start:], ctx.at(param))
+			return
+		}
+		ref = s[start+2 : start+end]
+		if value, ok = ctx.resolve(ref, param, stack); !ok {
+			return
+		}
+		sb.WriteString(s[:start] + value)
+		s = s[start+end+1:]
+	}
+}
+
+// resolve Find the value of reference ${ref}, interpolating any references within a parameter's value
+func (ctx *parseContext) resolve(ref, param string, stack []string) (value string, ok bool) {
+	var b []byte
+	var v interface{}
+	var err error
+
+	switch {
+	case strings.HasPrefix(ref, InterpolateEnvPrefix):
+		value, ok = os.LookupEnv(strings.TrimPrefix(ref, InterpolateEnvPrefix))
+		if !ok {
+			ctx.errList.Addf("setting for %s invalid, parameter %s: unresolved reference ${%s} [%s]",
+				ctx.elementID, param, ref, ctx.at(param))
+		}
+		return
+	case strings.HasPrefix(ref, InterpolateFilePrefix):
+		b, err = ioutil.ReadFile(strings.TrimPrefix(ref, InterpolateFilePrefix))
+		if err != nil {
+			ctx.errList.Addf("setting for %s invalid, parameter %s: unresolved reference ${%s}: %v [%s]",
+				ctx.elementID, param, ref, err, ctx.at(param))
+			return
+		}
+		return strings.TrimRight(string(b), "\r\n"), true
+	}
+
+	for i, resolving := range stack {
+		if resolving == ref {
+			ctx.errList.Addf("setting for %s invalid, parameter %s: reference cycle %s [%s]",
+				ctx.elementID, param, strings.Join(append(stack[i:], ref), " -> "), ctx.at(param))
+			return
+		}
+	}
+	v, ok = ctx.vars[ref]
+	if !ok {
+		ctx.errList.Addf("setting for %s invalid, parameter %s: unresolved reference ${%s} [%s]",
+			ctx.elementID, param, ref, ctx.at(param))
+		return
+	}
+	switch v.(type) {
+	case map[string]interface{}, []interface{}, nil:
+		ctx.errList.Addf("setting for %s invalid, parameter %s: reference ${%s} is not a single value [%s]",
+			ctx.elementID, param, ref, ctx.at(param))
+		return "", false
+	case float64:
+		value = strconv.FormatFloat(v.(float64), 'f', -1, 64)
+	default:
+		value = fmt.Sprintf("%v", v)
+	}
+	if strings.HasPrefix(value, ConfigSecretPrefix) {
+		return ctx.decrypt(value, param)
+	}
+	if !strings.Contains(value, "${") {
+		return value, true
+	}
+	return ctx.interpolate(value, param, append(append([]string{}, stack...), ref))
+}
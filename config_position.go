@@ -0,0 +1,385 @@
+package goutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TextPosition Line and column in a config file, both counted from 1
+// - Column is 0 if only the line is known
+type TextPosition struct {
+	Line   int
+	Column int
+}
+
+// String Position as line:column, or just the line if the column isn't known
+func (pos TextPosition) String() string {
+	if pos.Column == 0 {
+		return strconv.Itoa(pos.Line)
+	}
+	return fmt.Sprintf("%d:%d", pos.Line, pos.Column)
+}
+
+// KeyPositions Position of each key in a config file, by key path, e.g. db.host or allow[1]
+// - Array elements are keyed by index, e.g. [0].name, and "" is the position of the whole document or element
+type KeyPositions map[string]TextPosition
+
+// sub Positions of keys under prefix, with prefix removed from their paths
+func (positions KeyPositions) sub(prefix string) (sub KeyPositions) {
+	if positions == nil {
+		return
+	}
+	sub = make(KeyPositions)
+	for key, pos := range positions {
+		switch {
+		case key == prefix:
+			sub[""] = pos
+		case strings.HasPrefix(key, prefix+"."):
+			sub[strings.TrimPrefix(key, prefix+".")] = pos
+		case strings.HasPrefix(key, prefix+"["):
+			sub[strings.TrimPrefix(key, prefix)] = pos
+		}
+	}
+	return
+}
+
+// find Position of key path, or of the closest enclosing key whose position is known
+func (positions KeyPositions) find(key string) (pos TextPosition, ok bool) {
+	for {
+		if pos, ok = positions[key]; ok || key == "" {
+			return
+		}
+		if i := strings.LastIndexAny(key, ".["); i >= 0 {
+			key = key[:i]
+		} else {
+			key = ""
+		}
+	}
+}
+
+// configSyntaxError Config file that couldn't be decoded, with the position of the problem
+type configSyntaxError struct {
+	err error
+	pos TextPosition
+}
+
+// Error Message from the decoder
+func (syntaxErr *configSyntaxError) Error() string {
+	return syntaxErr.err.Error()
+}
+
+// yamlErrorLine Line number in YAML decoder messages, e.g. "yaml: line 3: did not find expected key"
+var yamlErrorLine = regexp.MustCompile(`line (\d+)`)
+
+// tomlErrorLine Line number in TOML decoder messages, e.g. "Near line 3 (last key parsed 'port'): ..."
+var tomlErrorLine = regexp.MustCompile(`^Near line (\d+)`)
+
+// syntaxError Add the position of a decoder error, where the decoder reports one
+// - JSON errors have line and column, YAML and TOML decoders only report the line
+func syntaxError(format string, b []byte, err error) error {
+	var jsonSyntaxErr *json.SyntaxError
+	var jsonTypeErr *json.UnmarshalTypeError
+	var match []string
+	var offset int64 = -1
+
+	switch format {
+	case ConfigFormatJSON:
+		if errors.As(err, &jsonSyntaxErr) {
+			// Offset is just after the character in error
+			offset = jsonSyntaxErr.Offset - 1
+		} else if errors.As(err, &jsonTypeErr) {
+			offset = jsonTypeErr.Offset
+		}
+		if offset >= 0 {
+			return &configSyntaxError{err: err, pos: offsetPosition(b, int(offset))}
+		}
+	case ConfigFormatYAML:
+		match = yamlErrorLine.FindStringSubmatch(err.Error())
+	case ConfigFormatTOML:
+		match = tomlErrorLine.FindStringSubmatch(err.Error())
+	}
+	if match != nil {
+		line, _ := strconv.Atoi(match[1])
+		return &configSyntaxError{err: err, pos: TextPosition{Line: line}}
+	}
+	return err
+}
+
+// offsetPosition Line and column of byte offset in b, columns are counted in characters
+func offsetPosition(b []byte, offset int) (pos TextPosition) {
+	if offset > len(b) {
+		offset = len(b)
+	}
+	lineStart := bytes.LastIndexByte(b[:offset], '\n') + 1
+	pos.Line = bytes.Count(b[:offset], []byte{'\n'}) + 1
+	pos.Column = utf8.RuneCount(b[lineStart:offset]) + 1
+	return
+}
+
+// joinKey Append key to key path
+func joinKey(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// jsonPositions Find the position of each key and array item in JSON document b
+// - Keys are positioned at their name, array items at their value
+func jsonPositions(b []byte) (positions KeyPositions) {
+	var walk func(path string) error
+
+	positions = make(KeyPositions)
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	// tokenStart Skip separators before the next token, as InputOffset is the end of the last
+	tokenStart := func() int {
+		offset := int(dec.InputOffset())
+		for offset < len(b) && strings.IndexByte(" \t\r\n,:", b[offset]) >= 0 {
+			offset++
+		}
+		return offset
+	}
+
+	walk = func(path string) error {
+		pos := offsetPosition(b, tokenStart())
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if _, found := positions[path]; !found {
+			positions[path] = pos
+		}
+		switch token {
+		case json.Delim('{'):
+			for dec.More() {
+				pos = offsetPosition(b, tokenStart())
+				if token, err = dec.Token(); err != nil {
+					return err
+				}
+				key, _ := token.(string)
+				positions[joinKey(path, key)] = pos
+				if err = walk(joinKey(path, key)); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+		case json.Delim('['):
+			for i := 0; dec.More(); i++ {
+				if err = walk(fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+		}
+		return err
+	}
+
+	// Syntax errors are reported by json.Unmarshal, positions found so far are kept
+	_ = walk("")
+	return
+}
+
+// yamlPositions Find the position of each key and sequence item in YAML document node
+func yamlPositions(node *yaml.Node, path string, positions KeyPositions) {
+	var i int
+
+	if _, found := positions[path]; !found {
+		positions[path] = TextPosition{Line: node.Line, Column: node.Column}
+	}
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			yamlPositions(child, path, positions)
+		}
+	case yaml.MappingNode:
+		for i = 0; i+1 < len(node.Content); i += 2 {
+			key := joinKey(path, node.Content[i].Value)
+			positions[key] = TextPosition{Line: node.Content[i].Line, Column: node.Content[i].Column}
+			yamlPositions(node.Content[i+1], key, positions)
+		}
+	case yaml.SequenceNode:
+		for i = range node.Content {
+			yamlPositions(node.Content[i], fmt.Sprintf("%s[%d]", path, i), positions)
+		}
+	}
+}
+
+// tomlPositions Find the position of each key and table in TOML document b, by scanning its lines
+// - Keys within inline tables and multi-line arrays are positioned at the key holding them
+func tomlPositions(b []byte) (positions KeyPositions) {
+	var table, line, key, value string
+	var depth, i int
+	var multiline string
+
+	positions = make(KeyPositions)
+	positions[""] = TextPosition{Line: 1, Column: 1}
+	tableCounts := make(map[string]int)
+
+	for i, line = range strings.Split(string(b), "\n") {
+		column := len(line) - len(strings.TrimLeft(line, " \t")) + 1
+		line = strings.TrimSpace(line)
+
+		// Skip the rest of multi-line strings and arrays
+		if multiline != "" {
+			if strings.Contains(line, multiline) {
+				multiline = ""
+			}
+			continue
+		}
+		if depth > 0 {
+			depth += strings.Count(line, "[") - strings.Count(line, "]")
+			continue
+		}
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		pos := TextPosition{Line: i + 1, Column: column}
+		switch {
+		case strings.HasPrefix(line, "[["):
+			name := tomlKey(strings.Trim(line[:strings.Index(line+"]]", "]]")], "[ \t"))
+			table = fmt.Sprintf("%s[%d]", name, tableCounts[name])
+			tableCounts[name]++
+			positions[table] = pos
+		case line[0] == '[':
+			table = tomlKey(strings.Trim(line[:strings.Index(line+"]", "]")], "[ \t"))
+			positions[table] = pos
+		case strings.Contains(line, "="):
+			j := strings.Index(line, "=")
+			key, value = tomlKey(line[:j]), strings.TrimSpace(line[j+1:])
+			positions[joinKey(table, key)] = pos
+			for _, quotes := range []string{`"""`, `'''`} {
+				if strings.HasPrefix(value, quotes) && !strings.Contains(value[3:], quotes) {
+					multiline = quotes
+				}
+			}
+			if strings.HasPrefix(value, "[") {
+				depth = strings.Count(value, "[") - strings.Count(value, "]")
+			}
+		}
+	}
+	return
+}
+
+// tomlKey Key path of a TOML key or table name, without quotes or spaces around dotted parts
+func tomlKey(s string) string {
+	parts := strings.Split(strings.TrimSpace(s), ".")
+	for i, part := range parts {
+		parts[i] = strings.Trim(strings.TrimSpace(part), `"'`)
+	}
+	return strings.Join(parts, ".")
+}
+
+// elementKey Key of field in element, its json tag name if the element uses it, otherwise its field name
+// - Otherwise a key differing only in case, as read with CaseInsensitiveKeys
+func elementKey(element map[string]interface{}, field configField) string {
+	if _, ok := element[field.key]; ok {
+		return field.key
+	}
+	if _, ok := element[field.Name]; ok {
+		return field.Name
+	}
+	for key := range element {
+		if strings.EqualFold(key, field.key) || strings.EqualFold(key, field.Name) {
+			return key
+		}
+	}
+	return field.Name
+}
+
+// paramIndexes Array indexes ending a parameter path part, e.g. allow[1] or grid[0][2]
+var paramIndexes = regexp.MustCompile(`^(.*?)((?:\[[0-9]+\])+)$`)
+
+// paramIndex One array index of paramIndexes
+var paramIndex = regexp.MustCompile(`\[([0-9]+)\]`)
+
+// paramKey Key path in element map m of dotted parameter path param, for data object (st)
+// - Fields are named by json tag if the element uses it, otherwise by field name
+// - Map keys, array indexes, and names not matching any field are used as they are
+// - Only a part ending in well-formed indexes is read as array indexes, so keys such as a[b are used as they are
+func paramKey(st reflect.Type, m map[string]interface{}, param string) string {
+	var keys []string
+	var key string
+	var indexes [][]string
+	var v interface{} = m
+
+	t := st
+	for _, name := range strings.Split(param, ".") {
+		indexes = nil
+		if match := paramIndexes.FindStringSubmatch(name); match != nil {
+			name = match[1]
+			indexes = paramIndex.FindAllStringSubmatch(match[2], -1)
+		}
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		element, _ := v.(map[string]interface{})
+
+		key = name
+		switch {
+		case t != nil && t.Kind() == reflect.Struct:
+			field, found := configFieldByName(t, name)
+			t = nil
+			if found {
+				t = field.Type
+				key = elementKey(element, field)
+			}
+		case t != nil && t.Kind() == reflect.Map:
+			t = t.Elem()
+		default:
+			t = nil
+		}
+		v = element[key]
+
+		// Follow array indexes, e.g. allow[1]
+		for _, index := range indexes {
+			n, _ := strconv.Atoi(index[1])
+			key += index[0]
+			arr, _ := v.([]interface{})
+			v = nil
+			if n < len(arr) {
+				v = arr[n]
+			}
+			if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+				t = t.Elem()
+			}
+		}
+		keys = append(keys, key)
+	}
+	return strings.Join(keys, ".")
+}
+
+// at Location of parameter param in this element, for error messages
+// - The file's short name with line and column, where the source has key positions
+// - Otherwise the element's location, see location
+func (parsed Parsed) at(st reflect.Type, param string) string {
+	if parsed.KeyPositions == nil {
+		return parsed.location()
+	}
+	pos, ok := parsed.KeyPositions.find(paramKey(st, parsed.ElementMap, param))
+	if !ok {
+		return parsed.location()
+	}
+	return parsed.DistinctName + ":" + pos.String()
+}
+
+// errorLocation Location of a decoding error in filename, with line and column where known
+func errorLocation(filename string, err error) string {
+	var syntaxErr *configSyntaxError
+
+	if errors.As(err, &syntaxErr) {
+		return filename + ":" + syntaxErr.pos.String()
+	}
+	return filename
+}
@@ -0,0 +1,189 @@
+package goutils
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// DefaultConfigPollInterval How often providers without change notification are polled by Watch
+const DefaultConfigPollInterval = 30 * time.Second
+
+// ConfigProvider A key/value backend holding config, such as an HTTP endpoint or a BoltDB bucket
+// - Each key holds an element, or an array of elements, in the format its extension names, JSON if none, see ConfigFormat
+// - Read keys with ProviderSources, so remote values merge with files and are reported as provider:key
+type ConfigProvider interface {
+	// Name Short name of the provider, its sources are named provider:key, e.g. http:app.json
+	Name() string
+
+	// Get Fetch the config held under key
+	Get(key string) (b []byte, err error)
+
+	// Watch Send the config held under key each time it changes, until done is closed
+	Watch(key string, done <-chan struct{}) (changes <-chan []byte, err error)
+}
+
+// ProviderSources Fetch each key from provider as a config source named provider:key, see ReadConfigSources
+// - List them after file sources for remote values to override files, or before for files to override them
+func ProviderSources(provider ConfigProvider, keys ...string) (sources []ConfigSource, err error) {
+	var b []byte
+
+	for _, key := range keys {
+		if b, err = provider.Get(key); err != nil {
+			return nil, err
+		}
+		sources = append(sources, BytesSource(provider.Name()+":"+key, b))
+	}
+	return
+}
+
+// pollConfig Send the config get returns for key each time it changes, checking every interval until done is closed
+// - initial is the config when polling starts, values that fail to fetch are skipped until the next poll
+func pollConfig(get func(key string) ([]byte, error), key string, initial []byte, interval time.Duration, done <-chan struct{}) <-chan []byte {
+	changes := make(chan []byte)
+	if interval <= 0 {
+		interval = DefaultConfigPollInterval
+	}
+
+	go func() {
+		defer close(changes)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		prev := initial
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			b, err := get(key)
+			if err != nil || bytes.Equal(b, prev) {
+				continue
+			}
+			prev = b
+			select {
+			case changes <- b:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return changes
+}
+
+// HTTPConfigProvider Config served by an HTTP endpoint, each key fetched with GET from URL/key
+// - Client is used for requests, http.DefaultClient if nil
+// - Interval is how often Watch polls, DefaultConfigPollInterval if 0
+type HTTPConfigProvider struct {
+	URL      string
+	Client   *http.Client
+	Interval time.Duration
+}
+
+// Name Sources from the endpoint are named http:key
+func (provider HTTPConfigProvider) Name() string {
+	return "http"
+}
+
+// Get Fetch key from the endpoint, which must respond 200 OK
+func (provider HTTPConfigProvider) Get(key string) (b []byte, err error) {
+	var resp *http.Response
+
+	client := provider.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	// Escape each part of the key, so keys may name paths, e.g. apps/web.json
+	parts := strings.Split(key, "/")
+	for i := range parts {
+		parts[i] = url.PathEscape(parts[i])
+	}
+	if resp, err = client.Get(strings.TrimSuffix(provider.URL, "/") + "/" + strings.Join(parts, "/")); err != nil {
+		return nil, fmt.Errorf("fetching config: %v [http:%s]", err, key)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching config: %s [http:%s]", resp.Status, key)
+	}
+	if b, err = ioutil.ReadAll(resp.Body); err != nil {
+		return nil, fmt.Errorf("fetching config: %v [http:%s]", err, key)
+	}
+	return
+}
+
+// Watch Poll key every Interval, sending its config each time it changes
+func (provider HTTPConfigProvider) Watch(key string, done <-chan struct{}) (changes <-chan []byte, err error) {
+	var b []byte
+
+	if b, err = provider.Get(key); err != nil {
+		return
+	}
+	return pollConfig(provider.Get, key, b, provider.Interval, done), nil
+}
+
+// BoltConfigProvider Config held in Bucket of the BoltDB database, see ConnectBolt
+// - Interval is how often Watch polls, DefaultConfigPollInterval if 0
+type BoltConfigProvider struct {
+	Bucket   string
+	Interval time.Duration
+}
+
+// Name Sources from the database are named bolt:key
+func (provider BoltConfigProvider) Name() string {
+	return "bolt"
+}
+
+// Get Fetch key from the bucket
+func (provider BoltConfigProvider) Get(key string) (b []byte, err error) {
+	if BoltDB == nil {
+		return nil, fmt.Errorf("fetching config: BoltDB not connected [bolt:%s]", key)
+	}
+	err = BoltDB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(provider.Bucket))
+		if bucket == nil {
+			return fmt.Errorf("fetching config: bucket %s not found [bolt:%s]", provider.Bucket, key)
+		}
+		value := bucket.Get([]byte(key))
+		if value == nil {
+			return fmt.Errorf("fetching config: key not found [bolt:%s]", key)
+		}
+
+		// Values are only valid during the transaction
+		b = append([]byte{}, value...)
+		return nil
+	})
+	return
+}
+
+// Put Store config b under key, creating the bucket if needed
+func (provider BoltConfigProvider) Put(key string, b []byte) (err error) {
+	if BoltDB == nil {
+		return fmt.Errorf("storing config: BoltDB not connected [bolt:%s]", key)
+	}
+	return BoltDB.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(provider.Bucket))
+		if err != nil {
+			return fmt.Errorf("storing config: %v [bolt:%s]", err, key)
+		}
+		return bucket.Put([]byte(key), b)
+	})
+}
+
+// Watch Poll key every Interval, sending its config each time it changes
+func (provider BoltConfigProvider) Watch(key string, done <-chan struct{}) (changes <-chan []byte, err error) {
+	var b []byte
+
+	if b, err = provider.Get(key); err != nil {
+		return
+	}
+	return pollConfig(provider.Get, key, b, provider.Interval, done), nil
+}
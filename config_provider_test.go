@@ -0,0 +1,102 @@
+package goutils
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestHTTPConfigProvider(t *testing.T) {
+	var config testConfig
+	var mu sync.Mutex
+
+	values := map[string]string{
+		"/servers":   `[{"name": "alpha", "port": 9999}, {"name": "beta", "verbose": true}]`,
+		"/apps/beta": `{"name": "beta", "debug": true}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		value, ok := values[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(value))
+	}))
+	defer server.Close()
+
+	provider := HTTPConfigProvider{URL: server.URL, Interval: 10 * time.Millisecond}
+	remote, err := ProviderSources(provider, "servers", "apps/beta")
+	Ok(t, err)
+	Equals(t, "http:servers", remote[0].Name)
+
+	// remote values are merged with files, and reported as provider:key
+	_, err = ReadConfigSources(&config, "Name", append(FileSources("test/config/servers.yaml"), remote...)...)
+	Assert(t, err != nil, "expected conflict and unused errors")
+	Assert(t, strings.Contains(err.Error(), `"9999" [http:servers:1:20]`), err.Error())
+	Assert(t, strings.Contains(err.Error(), "unused setting for beta, parameter verbose [http:servers:1:52]"), err.Error())
+
+	remote, err = ProviderSources(provider, "apps/beta")
+	Ok(t, err)
+	resultMap, err := ReadConfigSources(&config, "Name", append(FileSources("test/config/servers.yaml"), remote...)...)
+	Ok(t, err)
+	Equals(t, testConfig{Name: "beta", Port: 9090, Debug: true}, resultMap["beta"])
+
+	_, err = provider.Get("missing")
+	Equals(t, "fetching config: 404 Not Found [http:missing]", err.Error())
+
+	// changes are sent until done is closed
+	done := make(chan struct{})
+	changes, err := provider.Watch("apps/beta", done)
+	Ok(t, err)
+	mu.Lock()
+	values["/apps/beta"] = `{"name": "beta", "debug": false}`
+	mu.Unlock()
+	select {
+	case b := <-changes:
+		Equals(t, `{"name": "beta", "debug": false}`, string(b))
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected change")
+	}
+	close(done)
+	for range changes {
+	}
+}
+
+func TestBoltConfigProvider(t *testing.T) {
+	var config testConfig
+
+	dir, err := ioutil.TempDir("", "goutils")
+	Ok(t, err)
+	defer os.RemoveAll(dir)
+
+	saved := BoltDB
+	BoltDB, err = bolt.Open(filepath.Join(dir, "config.db"), 0600, nil)
+	Ok(t, err)
+	defer func() {
+		BoltDB.Close()
+		BoltDB = saved
+	}()
+
+	provider := BoltConfigProvider{Bucket: "config"}
+	_, err = provider.Get("app.yaml")
+	Equals(t, "fetching config: bucket config not found [bolt:app.yaml]", err.Error())
+
+	Ok(t, provider.Put("app.yaml", []byte("name: alpha\nport: 8080\n")))
+	remote, err := ProviderSources(provider, "app.yaml")
+	Ok(t, err)
+	Ok(t, ReadConfigSource(&config, remote[0]))
+	Equals(t, testConfig{Name: "alpha", Port: 8080}, config)
+
+	_, err = provider.Get("other.yaml")
+	Equals(t, "fetching config: key not found [bolt:other.yaml]", err.Error())
+}
@@ -0,0 +1,277 @@
+package goutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Patterns for values the config loader also accepts as strings, see parseValue
+const (
+	schemaDurationPattern = `^[-+]?(0|([0-9]*(\.[0-9]*)?(ns|us|µs|ms|s|m|h))+)$`
+	schemaIntPattern      = `^[-+]?[0-9]+$`
+	schemaUintPattern     = `^\+?[0-9]+$`
+	schemaFloatPattern    = `^[-+]?([0-9]+(\.[0-9]*)?|\.[0-9]+)([eE][-+]?[0-9]+)?$`
+	schemaBoolPattern     = `^(1|t|T|TRUE|true|True|0|f|F|FALSE|false|False)$`
+)
+
+// ConfigSchema Generate a JSON Schema document for config files read into the struct 'data' points to
+// - Accepts the same 'data' as ReadConfigFile, a file may hold one element or an array of elements
+// - Properties are named by json tag, and by field name as the loader accepts either, nested structs are nested objects
+// - Keys differing only in case, accepted with ConfigLoader.CaseInsensitiveKeys, are not listed
+// - Numbers and booleans may also be strings, as the loader accepts both
+// - Durations are strings matching time.ParseDuration, or integers with a unit tag, times are strings or epoch integers
+// - `required:"true"`, `default:"..."`, `usage:"..."` and `validate:"..."` tags become required, default, description and constraints
+// - Unknown properties are not allowed, as ReadConfigFiles reports them
+// - A struct nested within itself, e.g. Fallback *Config, refers to its definition in $defs, named by its type
+// - Errors with ErrInvalidTarget if 'data' is not a struct, or a pointer to one
+func ConfigSchema(data interface{}) (schema []byte, err error) {
+	st := reflect.TypeOf(data)
+	if st == nil {
+		return nil, fmt.Errorf("ConfigSchema: %w, not nil", ErrInvalidTarget)
+	}
+	for st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if st.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("ConfigSchema: %w, not %s", ErrInvalidTarget, reflect.TypeOf(data))
+	}
+
+	defs := &schemaDefs{
+		defs:      make(map[string]interface{}),
+		names:     make(map[reflect.Type]string),
+		expanding: make(map[reflect.Type]bool),
+	}
+	root := structSchema(st, defs)
+
+	// Only the top level of a file may include others, so the element is a copy with $include added
+	element := make(map[string]interface{})
+	for key, value := range root {
+		element[key] = value
+	}
+	properties := make(map[string]interface{})
+	for key, value := range root["properties"].(map[string]interface{}) {
+		properties[key] = value
+	}
+	element["properties"] = properties
+	defs.defs["element"] = element
+	properties[ConfigIncludeKey] = map[string]interface{}{
+		"anyOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+	}
+
+	ref := map[string]interface{}{"$ref": "#/$defs/element"}
+	document := map[string]interface{}{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title":   st.Name(),
+		"$defs":   defs.defs,
+		"anyOf": []interface{}{
+			ref,
+			map[string]interface{}{"type": "array", "items": ref},
+		},
+	}
+	return json.MarshalIndent(document, "", "  ")
+}
+
+// schemaDefs Definitions of struct types nested within themselves, for $ref, see ConfigSchema
+// - names holds the definition name of each struct type referred to, expanding the struct types being expanded
+type schemaDefs struct {
+	defs      map[string]interface{}
+	names     map[reflect.Type]string
+	expanding map[reflect.Type]bool
+}
+
+// ref Schema referring to the definition of struct type t, naming it by its type, numbered if names clash
+func (defs *schemaDefs) ref(t reflect.Type) map[string]interface{} {
+	name, ok := defs.names[t]
+	if !ok {
+		base := t.Name()
+		if base == "" {
+			base = "struct"
+		}
+		name = base
+		for i := 2; name == "element" || defs.named(name); i++ {
+			name = fmt.Sprintf("%s%d", base, i)
+		}
+		defs.names[t] = name
+	}
+	return map[string]interface{}{"$ref": "#/$defs/" + name}
+}
+
+// named Is name already the definition name of a struct type
+func (defs *schemaDefs) named(name string) bool {
+	for _, other := range defs.names {
+		if other == name {
+			return true
+		}
+	}
+	return false
+}
+
+// structSchema Schema of an object with a property for each exported field of data object (st)
+// - A tagged field has a property for its field name too, either name satisfies required
+// - If a field refers back to st, the schema is also added to defs
+func structSchema(st reflect.Type, defs *schemaDefs) (schema map[string]interface{}) {
+	var value string
+	var required []string
+	var requiredEither []interface{}
+	var ok bool
+
+	defs.expanding[st] = true
+	defer delete(defs.expanding, st)
+
+	properties := make(map[string]interface{})
+	for _, field := range configFields(st) {
+		property := typeSchema(field.Type, defs)
+		timeSchema(property, field.Type, field.Tag)
+		if value, ok = field.Tag.Lookup("usage"); ok {
+			property["description"] = value
+		}
+		if value, ok = field.Tag.Lookup("default"); ok {
+			property["default"] = value
+		}
+		if value, ok = field.Tag.Lookup("validate"); ok {
+			ruleSchema(property, field.Type, value)
+		}
+		if field.Tag.Get("required") == "true" {
+			if field.key == field.Name {
+				required = append(required, field.key)
+			} else {
+				requiredEither = append(requiredEither, map[string]interface{}{
+					"anyOf": []interface{}{
+						map[string]interface{}{"required": []string{field.key}},
+						map[string]interface{}{"required": []string{field.Name}},
+					},
+				})
+			}
+		}
+		properties[field.key] = property
+		if _, ok = properties[field.Name]; !ok {
+			properties[field.Name] = property
+		}
+	}
+
+	schema = map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	if len(requiredEither) > 0 {
+		schema["allOf"] = requiredEither
+	}
+	if name, ok := defs.names[st]; ok {
+		defs.defs[name] = schema
+	}
+	return
+}
+
+// typeSchema Schema of the values parseValue accepts for type t
+// - A struct already being expanded refers to its definition in defs
+func typeSchema(t reflect.Type, defs *schemaDefs) (schema map[string]interface{}) {
+	switch {
+	case t == durationType:
+		return map[string]interface{}{"type": "string", "pattern": schemaDurationPattern}
+	case t == timeType:
+		return map[string]interface{}{"type": []string{"string", "integer"}}
+	case t.Kind() != reflect.Ptr && reflect.PtrTo(t).Implements(textUnmarshalerType):
+		return map[string]interface{}{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": []string{"boolean", "string"}, "pattern": schemaBoolPattern}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": []string{"integer", "string"}, "pattern": schemaIntPattern}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]interface{}{"type": []string{"integer", "string"}, "pattern": schemaUintPattern, "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": []string{"number", "string"}, "pattern": schemaFloatPattern}
+	case reflect.Ptr:
+		return typeSchema(t.Elem(), defs)
+	case reflect.Struct:
+		if defs.expanding[t] {
+			return defs.ref(t)
+		}
+		return structSchema(t, defs)
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), defs)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem(), defs)}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// timeSchema Adjust the schema of a time or duration field of type t for its layout and unit tags, see parseTime
+func timeSchema(schema map[string]interface{}, t reflect.Type, tag reflect.StructTag) {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			schema = items
+		}
+	}
+	_, hasLayout := tag.Lookup("layout")
+	_, hasUnit := tag.Lookup("unit")
+	switch {
+	case t == timeType && hasLayout:
+		schema["type"] = "string"
+	case t == durationType && hasUnit:
+		schema["type"] = []string{"integer", "string"}
+		schema["pattern"] = schemaDurationPattern + "|" + schemaIntPattern
+	}
+}
+
+// ruleSchema Add constraints for the rules of a `validate:"..."` tag to the schema of type t
+// - Rules that can't be expressed for the type, such as min and max of a duration, are left to the loader
+func ruleSchema(schema map[string]interface{}, t reflect.Type, rules string) {
+	var keyword string
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for _, rule := range splitRules(rules) {
+		switch rule.name {
+		case "min", "max":
+			if t == durationType {
+				continue
+			}
+			switch t.Kind() {
+			case reflect.String:
+				keyword = "Length"
+			case reflect.Slice, reflect.Array:
+				keyword = "Items"
+			case reflect.Map:
+				keyword = "Properties"
+			default:
+				if bound, err := strconv.ParseFloat(rule.arg, 64); err == nil {
+					schema[map[string]string{"min": "minimum", "max": "maximum"}[rule.name]] = bound
+				}
+				continue
+			}
+			if limit, err := strconv.Atoi(rule.arg); err == nil {
+				schema[rule.name+keyword] = limit
+			}
+		case "oneof":
+			if t.Kind() == reflect.String {
+				schema["enum"] = strings.Split(rule.arg, "|")
+			}
+		case "email":
+			schema["format"] = "email"
+		case "url":
+			schema["format"] = "uri"
+		case "regex":
+			if t.Kind() == reflect.String {
+				schema["pattern"] = rule.arg
+			}
+		}
+	}
+}
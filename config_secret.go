@@ -0,0 +1,154 @@
+package goutils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// ConfigSecretPrefix Prefix of an encrypted config value, followed by the base64 AES-GCM nonce and ciphertext
+const ConfigSecretPrefix = "enc:v1:"
+
+// secretMask Shown in place of a decrypted value in error messages
+const secretMask = "(secret)"
+
+// KeyProvider Supplies the AES key for encrypted config values, 16, 24 or 32 bytes for AES-128, AES-192 or AES-256
+type KeyProvider interface {
+	Key() (key []byte, err error)
+}
+
+// EnvKeyProvider Key from environment variable Name, base64 encoded
+type EnvKeyProvider struct {
+	Name string
+}
+
+// Key Decode the key from the environment variable
+func (provider EnvKeyProvider) Key() (key []byte, err error) {
+	text, ok := os.LookupEnv(provider.Name)
+	if !ok {
+		return nil, fmt.Errorf("key environment variable %s not set", provider.Name)
+	}
+	return decodeKey(text, "environment variable "+provider.Name)
+}
+
+// FileKeyProvider Key from the file at Path, base64 encoded
+// - Suits keys mounted as files, e.g. /run/secrets/config_key
+type FileKeyProvider struct {
+	Path string
+}
+
+// Key Read and decode the key file
+func (provider FileKeyProvider) Key() (key []byte, err error) {
+	b, err := ioutil.ReadFile(provider.Path)
+	if err != nil {
+		return nil, fmt.Errorf("reading key file: %v", err)
+	}
+	return decodeKey(string(b), "file "+provider.Path)
+}
+
+// decodeKey Decode a base64 key, checking it is a valid AES key size
+func decodeKey(text, source string) (key []byte, err error) {
+	key, err = base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+	if err != nil {
+		return nil, fmt.Errorf("key in %s is not base64", source)
+	}
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("key in %s is %d bytes, must be 16, 24 or 32", source, len(key))
+	}
+}
+
+// GenerateConfigKey Make a random AES-256 key, base64 encoded for an EnvKeyProvider or FileKeyProvider
+func GenerateConfigKey() (key string, err error) {
+	b := make([]byte, 32)
+	if _, err = io.ReadFull(rand.Reader, b); err != nil {
+		return
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// EncryptConfigValue Encrypt plaintext for pasting into a config file, as enc:v1: followed by base64
+// - Read with ConfigLoader.Keys set to a KeyProvider for the same key
+// - Encrypting the same value twice gives different results, as each has its own random nonce
+func EncryptConfigValue(plaintext string, keys KeyProvider) (value string, err error) {
+	var gcm cipher.AEAD
+
+	if gcm, err = configCipher(keys); err != nil {
+		return
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return ConfigSecretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptConfigValue Decrypt a value made by EncryptConfigValue
+// - Errors never include the value, encrypted or not
+func decryptConfigValue(value string, keys KeyProvider) (plaintext string, err error) {
+	var gcm cipher.AEAD
+	var sealed, opened []byte
+
+	if keys == nil {
+		return "", fmt.Errorf("no KeyProvider to decrypt secret")
+	}
+	if gcm, err = configCipher(keys); err != nil {
+		return
+	}
+	sealed, err = base64.StdEncoding.DecodeString(strings.TrimPrefix(value, ConfigSecretPrefix))
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("secret is not valid %s base64", ConfigSecretPrefix)
+	}
+	opened, err = gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("secret could not be decrypted, wrong key or corrupted value")
+	}
+	return string(opened), nil
+}
+
+// configCipher AES-GCM cipher with the provider's key
+func configCipher(keys KeyProvider) (gcm cipher.AEAD, err error) {
+	var key []byte
+	var block cipher.Block
+
+	if key, err = keys.Key(); err != nil {
+		return
+	}
+	if block, err = aes.NewCipher(key); err != nil {
+		return
+	}
+	return cipher.NewGCM(block)
+}
+
+// decrypt Decrypt secret value s for parameter param with the loader's KeyProvider
+// - param is recorded in secrets, so its value is never shown in error messages
+func (ctx *parseContext) decrypt(s, param string) (plaintext string, ok bool) {
+	var err error
+
+	ctx.secrets[param] = true
+	plaintext, err = decryptConfigValue(s, ctx.loader.Keys)
+	if err != nil {
+		ctx.errList.Addf("setting for %s invalid, parameter %s: %v [%s]", ctx.elementID, param, err, ctx.at(param))
+		return "", false
+	}
+	return plaintext, true
+}
+
+// redactSecret Replace the text of secret value fv in message, for validation problems
+func redactSecret(message string, fv reflect.Value) string {
+	text := fmt.Sprintf("%v", fv.Interface())
+	if len(text) == 0 {
+		return message
+	}
+	return strings.Replace(message, text, secretMask, -1)
+}
@@ -0,0 +1,224 @@
+package goutils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/AndrewDonelson/golog"
+)
+
+// ConfigSource A named config to read, from a file, an embedded filesystem, an io.Reader such as stdin, memory, or a ConfigProvider
+// - Name identifies the source in error messages and provenance, its extension chooses the format, see ConfigFormat
+// - A source with no Reader is the file Name, read as ReadConfigFiles reads files, including $include directives
+// - A Reader is read once, so a source with one can only be used for a single read
+type ConfigSource struct {
+	Name   string
+	Reader io.Reader
+}
+
+// FileSources Make a source for each config file, see ConfigSource
+func FileSources(filenames ...string) (sources []ConfigSource) {
+	for _, filename := range filenames {
+		sources = append(sources, ConfigSource{Name: filename})
+	}
+	return
+}
+
+// BytesSource Make a source for config b held in memory, e.g. a test fixture, named name
+func BytesSource(name string, b []byte) ConfigSource {
+	return ConfigSource{Name: name, Reader: bytes.NewReader(b)}
+}
+
+// FSSources Make a source for each config file in fsys named by paths, e.g. an embed.FS of defaults
+// - Paths may be files, directories or glob patterns, expanded as ExpandConfigPaths does
+// - Sources are named by their path within fsys
+// - Files are read now, so a path that can't be read is an error
+func FSSources(fsys fs.FS, paths ...string) (sources []ConfigSource, err error) {
+	var filenames, matches []string
+	var entries []fs.DirEntry
+	var b []byte
+
+	for _, p := range paths {
+		if fileInfo, statErr := fs.Stat(fsys, p); statErr == nil && fileInfo.IsDir() {
+			if entries, err = fs.ReadDir(fsys, p); err != nil {
+				return nil, fmt.Errorf("reading config directory: %v", err)
+			}
+			for _, entry := range entries {
+				switch strings.ToLower(path.Ext(entry.Name())) {
+				case ".json", ".yaml", ".yml", ".toml":
+					if !entry.IsDir() {
+						filenames = append(filenames, path.Join(p, entry.Name()))
+					}
+				}
+			}
+			continue
+		}
+		if strings.ContainsAny(p, "*?[") {
+			if matches, err = fs.Glob(fsys, p); err != nil || len(matches) == 0 {
+				return nil, fmt.Errorf("config pattern matches no files [%s]", p)
+			}
+			sort.Strings(matches)
+			filenames = append(filenames, matches...)
+			continue
+		}
+		filenames = append(filenames, p)
+	}
+
+	for _, filename := range filenames {
+		if b, err = fs.ReadFile(fsys, filename); err != nil {
+			return nil, fmt.Errorf("reading file: %v", err)
+		}
+		sources = append(sources, BytesSource(filename, b))
+	}
+	return
+}
+
+// ReadConfigSource Read a single config source into the struct 'data' points to, see ReadConfigFile
+func ReadConfigSource(data interface{}, source ConfigSource) (err error) {
+	return (&ConfigLoader{}).ReadConfigSource(data, source)
+}
+
+// ReadConfigSource Read a single config source using loader options, see ReadConfigFile
+func (loader *ConfigLoader) ReadConfigSource(data interface{}, source ConfigSource) (err error) {
+	var b []byte
+
+	if source.Reader == nil {
+		return loader.ReadConfigFile(data, source.Name)
+	}
+	st, sv, err := configTarget("ReadConfigSource", data)
+	if err != nil {
+		return
+	}
+	if b, err = ioutil.ReadAll(source.Reader); err != nil {
+		return fmt.Errorf("reading source: %v [%s]", err, source.Name)
+	}
+	return loader.readConfigBytes(st, sv, source.Name, source.Name, b)
+}
+
+// ReadConfigSources Read a list of config sources into a map of structs, see ReadConfigFiles
+// - Sources are layered in order, as files are, so embedded defaults can be listed ahead of files that override them
+func ReadConfigSources(data interface{}, idName string, sources ...ConfigSource) (resultMap ResultMap, err error) {
+	return (&ConfigLoader{}).ReadConfigSources(data, idName, sources...)
+}
+
+// ReadConfigSources Read a list of config sources using loader options, see ReadConfigSources
+func (loader *ConfigLoader) ReadConfigSources(data interface{}, idName string, sources ...ConfigSource) (resultMap ResultMap, err error) {
+	resultMap, _, _, _, err = loader.readConfigSources(data, idName, sources)
+	return
+}
+
+// loadedSource A config source read and decoded, ready to split into elements
+type loadedSource struct {
+	file      FileDetail
+	config    interface{}
+	positions KeyPositions
+}
+
+// loadSources Read and decode sources in order, each file preceded by the files it includes
+// - Files are validated and given distinct names by DistinctFilenames, other sources keep their Name
+// - Sources that can't be read or decoded are reported and skipped
+func loadSources(sources []ConfigSource, errList *ErrList) (loaded []loadedSource) {
+	var expanded []ConfigSource
+
+	// Add files named by $include directives
+	inc := newIncluder(errList)
+	for _, source := range sources {
+		if source.Reader != nil {
+			expanded = append(expanded, source)
+			continue
+		}
+		listed := len(inc.expanded)
+		inc.include(source.Name, nil)
+		for _, filename := range inc.expanded[listed:] {
+			expanded = append(expanded, ConfigSource{Name: filename})
+		}
+	}
+	return inc.load(expanded)
+}
+
+// load Read and decode sources, with $include directives already expanded, in order, see loadSources
+// - Files the includer has decoded are not read again
+func (inc *includer) load(sources []ConfigSource) (loaded []loadedSource) {
+	var filenames []string
+	var fileDetails []FileDetail
+	var config interface{}
+	var positions KeyPositions
+	var b []byte
+	var err error
+
+	// Validate file list
+	errList := inc.errList
+	for _, source := range sources {
+		if source.Reader == nil {
+			filenames = append(filenames, source.Name)
+		}
+	}
+	fileDetails = DistinctFilenames(filenames, errList)
+
+	names := make(map[string]bool)
+	for _, source := range sources {
+		if source.Reader == nil {
+			// Files not in fileDetails have been reported by DistinctFilenames
+			if len(fileDetails) == 0 || fileDetails[0].Name != source.Name {
+				continue
+			}
+			file := fileDetails[0]
+			fileDetails = fileDetails[1:]
+
+			decoded, ok := inc.decoded[file.Name]
+			config, positions = decoded.config, decoded.positions
+			if !ok {
+				if b, err = ioutil.ReadFile(file.Name); err != nil {
+					errList.Addf("reading file: %v", err)
+					continue
+				}
+
+				// Parse config file as map[string]interface{}, or slice of these
+				config, positions, err = decodeConfig(file.Name, b)
+				if err != nil {
+					golog.Log.Debugf("Parsing issue, skipping [%s]", file.Name)
+					errList.Addf("%v, skipping [%s]", err, errorLocation(file.Name, err))
+					continue
+				}
+			}
+			loaded = append(loaded, loadedSource{file: file, config: config, positions: positions})
+			continue
+		}
+
+		if names[source.Name] {
+			errList.Addf("source is duplicate, skipping [%s]", source.Name)
+			continue
+		}
+		names[source.Name] = true
+		if b, err = ioutil.ReadAll(source.Reader); err != nil {
+			errList.Addf("reading source: %v [%s]", err, source.Name)
+			continue
+		}
+		config, positions, err = decodeConfig(source.Name, b)
+		if err != nil {
+			golog.Log.Debugf("Parsing issue, skipping [%s]", source.Name)
+			errList.Addf("%v, skipping [%s]", err, errorLocation(source.Name, err))
+			continue
+		}
+
+		// Included paths are relative to a file, so sources without one can't include others
+		if element, isMap := config.(map[string]interface{}); isMap {
+			if _, ok := element[ConfigIncludeKey]; ok {
+				delete(element, ConfigIncludeKey)
+				errList.Addf("%s is only read from files, skipping it [%s]", ConfigIncludeKey, source.Name)
+			}
+		}
+		loaded = append(loaded, loadedSource{
+			file:      FileDetail{Name: source.Name, DistinctName: source.Name},
+			config:    config,
+			positions: positions,
+		})
+	}
+	return
+}
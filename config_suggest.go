@@ -0,0 +1,60 @@
+package goutils
+
+import (
+	"reflect"
+	"strings"
+)
+
+// suggestParam Suggest the field closest to unknown parameter param, e.g. ", did you mean timeout?"
+// - param is a dotted parameter path, its last key is compared with the json tags and names of fields at that level
+// - Only close matches are suggested, up to a third of the key's length plus one edit, otherwise the suggestion is empty
+func suggestParam(st reflect.Type, param string) (suggestion string) {
+	var key, best string
+	var distance, bestDistance int
+
+	// Find the nested struct holding param, through slice indexes and map keys, e.g. Servers[0].hots or Pools.read.hots
+	names := strings.Split(param, ".")
+	key = names[len(names)-1]
+	for i := 0; i < len(names)-1; i++ {
+		name := names[i]
+		if match := paramIndexes.FindStringSubmatch(name); match != nil {
+			name = match[1]
+		}
+		field, ok := configFieldByName(st, name)
+		if !ok {
+			return
+		}
+		st = itemType(field.Type)
+		if st.Kind() == reflect.Map {
+			// The next name is a map key
+			st = itemType(st.Elem())
+			i++
+		}
+		if st.Kind() != reflect.Struct || i >= len(names)-1 {
+			return
+		}
+	}
+
+	bestDistance = len(key)/3 + 2
+	for _, field := range configFields(st) {
+		candidates := []string{field.key, field.Name}
+		for _, candidate := range candidates {
+			distance = StringEditDistance(strings.ToLower(key), strings.ToLower(candidate))
+			if distance < bestDistance {
+				best, bestDistance = candidates[0], distance
+			}
+		}
+	}
+	if best == "" {
+		return
+	}
+	return ", did you mean " + best + "?"
+}
+
+// itemType Type t through any pointers, slices and arrays, e.g. Server for []*Server
+func itemType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	return t
+}
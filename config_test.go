@@ -0,0 +1,963 @@
+package goutils
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+type testConfig struct {
+	Name    string        `json:"name"`
+	Port    int           `json:"port"`
+	Timeout time.Duration `json:"timeout"`
+	Start   time.Time     `json:"start"`
+	Debug   bool          `json:"debug"`
+	Rate    float64       `json:"rate"`
+}
+
+func TestReadConfigFileFormats(t *testing.T) {
+	exp := testConfig{
+		Name:    "alpha",
+		Port:    8080,
+		Timeout: 30 * time.Second,
+		Start:   time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC),
+		Debug:   true,
+		Rate:    1.5,
+	}
+
+	for _, filename := range []string{"test/config/single.json", "test/config/single.yaml", "test/config/single.toml"} {
+		var config testConfig
+		err := ReadConfigFile(&config, filename)
+		Ok(t, err)
+		Equals(t, exp, config)
+	}
+}
+
+func TestReadConfigFilesFormats(t *testing.T) {
+	var config testConfig
+
+	resultMap, err := ReadConfigFiles(&config, "Name", "test/config/servers.yaml", "test/config/servers.toml")
+	Ok(t, err)
+	Equals(t, 2, len(resultMap))
+	Equals(t, testConfig{Name: "alpha", Port: 8080, Debug: true}, resultMap["alpha"])
+	Equals(t, testConfig{Name: "beta", Port: 9090}, resultMap["beta"])
+
+	// conflicting values are reported with the distinct filename, line and column of each source
+	_, err = ReadConfigFiles(&config, "Name", "test/config/servers.yaml", "test/config/conflict.toml")
+	Assert(t, err != nil, "expected conflict error")
+	Assert(t, strings.HasPrefix(err.Error(), "settings for alpha conflict, parameter Port: "), err.Error())
+	Assert(t, strings.Contains(err.Error(), `"8080" [servers.yaml:2:3]`), err.Error())
+	Assert(t, strings.Contains(err.Error(), `"9999" [conflict.toml:2:1]`), err.Error())
+}
+
+func TestReadConfigFilesErrors(t *testing.T) {
+	var config testConfig
+	var elementErr *ConfigElementError
+
+	// a bad target or id field is an error, not a panic
+	err := ReadConfigFile(config, "test/config/single.json")
+	Assert(t, errors.Is(err, ErrInvalidTarget), fmt.Sprint(err))
+	Equals(t, "ReadConfigFile: 'data' must be a non-nil pointer to a struct, not goutils.testConfig", err.Error())
+	name := "alpha"
+	err = ReadConfigFile(&name, "test/config/single.json")
+	Assert(t, errors.Is(err, ErrInvalidTarget), fmt.Sprint(err))
+	_, err = ReadConfigFiles((*testConfig)(nil), "Name", "test/config/servers.yaml")
+	Assert(t, errors.Is(err, ErrInvalidTarget), fmt.Sprint(err))
+	_, err = ReadConfigFiles(&config, "Id", "test/config/servers.yaml")
+	Assert(t, errors.Is(err, ErrUnknownIDField), fmt.Sprint(err))
+	_, err = (&ConfigLoader{}).Explain(&config, "Id", "test/config/servers.yaml")
+	Assert(t, errors.Is(err, ErrUnknownIDField), fmt.Sprint(err))
+
+	// elements that aren't objects are skipped, reporting their position
+	resultMap, err := ReadConfigFiles(&config, "Name", "test/config/elements-bad.json")
+	Equals(t, "config element is not an object, found string [elements-bad.json:elem#2], skipping", err.Error())
+	Equals(t, 2, len(resultMap))
+	Assert(t, errors.Is(err, ErrElementNotObject), err.Error())
+	Assert(t, errors.As(err, &elementErr), err.Error())
+	Equals(t, 2, elementErr.Position)
+
+	err = ReadConfigFile(&config, "test/config/elements-bad.json")
+	Assert(t, errors.As(err, &elementErr), fmt.Sprint(err))
+	Equals(t, "slice", elementErr.Type)
+}
+
+func TestReadConfigSources(t *testing.T) {
+	var config testConfig
+
+	err := ReadConfigSource(&config, ConfigSource{Name: "stdin.json", Reader: strings.NewReader(`{"name": "alpha", "port": 8080}`)})
+	Ok(t, err)
+	Equals(t, testConfig{Name: "alpha", Port: 8080}, config)
+
+	// embedded defaults are overridden by the sources listed after them
+	fsys := fstest.MapFS{
+		"config/defaults.yaml": {Data: []byte("- name: alpha\n  timeout: 30s\n- name: beta\n  timeout: 1m\n")},
+		"config/README.txt":    {Data: []byte("not config")},
+	}
+	defaults, err := FSSources(fsys, "config")
+	Ok(t, err)
+	Equals(t, 1, len(defaults))
+	sources := append(defaults, FileSources("test/config/servers.yaml")...)
+	sources = append(sources, BytesSource("override.toml", []byte("name = \"beta\"\ndebug = true\n")))
+	loader := &ConfigLoader{Merge: MergeLastWins}
+	resultMap, err := loader.ReadConfigSources(&config, "Name", sources...)
+	Ok(t, err)
+	Equals(t, testConfig{Name: "alpha", Port: 8080, Timeout: 30 * time.Second}, resultMap["alpha"])
+	Equals(t, testConfig{Name: "beta", Port: 9090, Timeout: time.Minute, Debug: true}, resultMap["beta"])
+
+	// errors are reported against the source name
+	_, err = ReadConfigSources(&config, "Name", BytesSource("bad.json", []byte(`{"name": "alpha", "port": "x"}`)))
+	Equals(t, "setting for alpha invalid, parameter Port: integer x [bad.json:1:19]", err.Error())
+	_, err = FSSources(fsys, "config/*.json")
+	Equals(t, "config pattern matches no files [config/*.json]", err.Error())
+}
+
+type testFleetConfig struct {
+	Servers []testDBConfig `json:"servers"`
+}
+
+func TestReadConfigFileTOMLTables(t *testing.T) {
+	var config testFleetConfig
+
+	// an array of tables matching a field is that field, not an array of elements
+	Ok(t, ReadConfigFile(&config, "test/config/fleet.toml"))
+	Equals(t, testFleetConfig{Servers: []testDBConfig{{Host: "a", Port: 1}, {Host: "b", Port: 2}}}, config)
+	Ok(t, ReadConfigSource(&config, BytesSource("fleet.json", []byte(`{"servers": [{"host": "a", "port": 1}]}`))))
+	Equals(t, testFleetConfig{Servers: []testDBConfig{{Host: "a", Port: 1}}}, config)
+}
+
+func TestReadConfigFileUnused(t *testing.T) {
+	var config testConfig
+
+	err := ReadConfigFile(&config, "test/config/unused.yml")
+	Ok(t, err)
+
+	_, err = ReadConfigFiles(&config, "Name", "test/config/unused.yml")
+	Equals(t, "unused setting for alpha, parameter prot, did you mean port? [unused.yml:2:1]", err.Error())
+
+	err = (&ConfigLoader{UnknownKeys: UnknownKeysStrict}).ReadConfigFile(&config, "test/config/unused.yml")
+	Equals(t, "unused setting for default, parameter prot, did you mean port? [unused.yml:2:1]", err.Error())
+
+	for _, policy := range []UnknownKeyPolicy{UnknownKeysLenient, UnknownKeysIgnore} {
+		_, err = (&ConfigLoader{UnknownKeys: policy}).ReadConfigFiles(&config, "Name", "test/config/unused.yml")
+		Ok(t, err)
+	}
+
+	Equals(t, ", did you mean timeout?", suggestParam(reflect.TypeOf(testConfig{}), "timout"))
+	Equals(t, ", did you mean host?", suggestParam(reflect.TypeOf(testNestedConfig{}), "DB.hots"))
+	Equals(t, "", suggestParam(reflect.TypeOf(testConfig{}), "verbose"))
+
+	// keys of structs in slices are reported by index
+	var fleet testFleetConfig
+	err = (&ConfigLoader{UnknownKeys: UnknownKeysStrict}).ReadConfigSource(&fleet,
+		BytesSource("fleet.json", []byte(`{"servers": [{"host": "a"}, {"hots": "b"}]}`)))
+	Equals(t, "unused setting for default, parameter Servers[1].hots, did you mean host? [fleet.json:1:30]", err.Error())
+	Equals(t, ", did you mean port?", suggestParam(reflect.TypeOf(struct{ Pools map[string][]testDBConfig }{}), "Pools.read[0].prot"))
+}
+
+type testDBConfig struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+type testNestedConfig struct {
+	Name    string         `json:"name"`
+	DB      testDBConfig   `json:"db"`
+	Allow   []string       `json:"allow"`
+	Quotas  map[string]int `json:"quotas"`
+	Retries *int           `json:"retries"`
+	Backup  *testDBConfig  `json:"backup"`
+}
+
+func TestReadConfigFileNested(t *testing.T) {
+	var config testNestedConfig
+
+	err := ReadConfigFile(&config, "test/config/nested.json")
+	Ok(t, err)
+	Equals(t, testDBConfig{Host: "db.local", Port: 5432}, config.DB)
+	Equals(t, []string{"10.0.0.1", "10.0.0.2"}, config.Allow)
+	Equals(t, map[string]int{"read": 100, "write": 10}, config.Quotas)
+	Assert(t, config.Retries != nil && *config.Retries == 3, "expected retries 3, got %v", config.Retries)
+	Assert(t, config.Backup == nil, "expected no backup, got %v", config.Backup)
+
+	err = ReadConfigFile(&config, "test/config/nested-bad.yaml")
+	Equals(t, "setting for default invalid, parameter DB.Port: integer fivefourthreetwo [nested-bad.yaml:4:3]", err.Error())
+
+	// nested parameters are compared, and reported, individually
+	_, err = ReadConfigFiles(&config, "Name", "test/config/nested.json", "test/config/nested-bad.yaml")
+	Assert(t, strings.Contains(err.Error(), "settings for alpha conflict, parameter DB.Port: "), err.Error())
+	Assert(t, strings.Contains(err.Error(), `"fivefourthreetwo" [nested-bad.yaml:4:3]`), err.Error())
+	Assert(t, !strings.Contains(err.Error(), "parameter DB.Host"), err.Error())
+}
+
+type testPort uint16
+
+type testLevel int
+
+func (level *testLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "info":
+		*level = 1
+	case "warn":
+		*level = 2
+	default:
+		return fmt.Errorf("unknown level %s", text)
+	}
+	return nil
+}
+
+type testNumericConfig struct {
+	Port  testPort  `json:"port"`
+	Small int8      `json:"small"`
+	Count uint32    `json:"count"`
+	Big   int32     `json:"big"`
+	Ratio float32   `json:"ratio"`
+	Level testLevel `json:"level"`
+	Addr  net.IP    `json:"addr"`
+}
+
+func TestReadConfigFileNumeric(t *testing.T) {
+	var config testNumericConfig
+
+	err := ReadConfigFile(&config, "test/config/numeric.json")
+	Ok(t, err)
+	Equals(t, testNumericConfig{
+		Port:  8080,
+		Small: -12,
+		Count: 4000000000,
+		Big:   1000000,
+		Ratio: 0.25,
+		Level: 2,
+		Addr:  net.ParseIP("192.168.1.10"),
+	}, config)
+
+	err = ReadConfigFile(&config, "test/config/numeric-bad.json")
+	Equals(t, "setting for default invalid, parameter Small: integer 300 overflows int8 [numeric-bad.json:2:3]", err.Error())
+
+	err = ReadConfigFile(&config, "test/config/numeric-ip.json")
+	Equals(t, "setting for default invalid, parameter Addr: net.IP 300.1.1.1 [numeric-ip.json:2:3]", err.Error())
+}
+
+type testEnvConfig struct {
+	Name     string        `json:"name"`
+	Port     int           `json:"port"`
+	Timeout  time.Duration `json:"timeout"`
+	Password string        `json:"password" env:"TEST_DB_PASSWORD"`
+	DB       testDBConfig  `json:"db"`
+	Allow    []string      `json:"allow"`
+}
+
+func TestConfigLoaderEnv(t *testing.T) {
+	var config testEnvConfig
+
+	os.Setenv("TEST_DB_PASSWORD", "secret")
+	os.Setenv("APP_TIMEOUT", "45s")
+	os.Setenv("APP_DB_HOST", "db.env")
+	os.Setenv("APP_ALLOW", "10.0.0.3, 10.0.0.4")
+	defer os.Unsetenv("TEST_DB_PASSWORD")
+	defer os.Unsetenv("APP_TIMEOUT")
+	defer os.Unsetenv("APP_DB_HOST")
+	defer os.Unsetenv("APP_ALLOW")
+
+	// environment is opt-in
+	err := ReadConfigFile(&config, "test/config/single.json")
+	Ok(t, err)
+	Equals(t, "", config.Password)
+
+	// tagged fields only
+	config = testEnvConfig{}
+	err = (&ConfigLoader{Env: true}).ReadConfigFile(&config, "test/config/single.yaml")
+	Ok(t, err)
+	Equals(t, "secret", config.Password)
+	Equals(t, 30*time.Second, config.Timeout)
+
+	// prefix convention, nested and slice fields
+	config = testEnvConfig{}
+	err = (&ConfigLoader{EnvPrefix: "APP_"}).ReadConfigFile(&config, "test/config/env.json")
+	Ok(t, err)
+	Equals(t, "secret", config.Password)
+	Equals(t, 45*time.Second, config.Timeout)
+	Equals(t, testDBConfig{Host: "db.env", Port: 5432}, config.DB)
+	Equals(t, []string{"10.0.0.3", "10.0.0.4"}, config.Allow)
+
+	// environment and file values conflict
+	os.Setenv("APP_DB_PORT", "6543")
+	defer os.Unsetenv("APP_DB_PORT")
+	resultMap, err := (&ConfigLoader{EnvPrefix: "APP_"}).ReadConfigFiles(&config, "Name", "test/config/env.json")
+	Assert(t, strings.HasPrefix(err.Error(), "settings for alpha conflict, parameter DB.Port: "), err.Error())
+	Assert(t, strings.Contains(err.Error(), `"6543" [env:APP_DB_PORT]`), err.Error())
+	Assert(t, strings.Contains(err.Error(), `"5432" [env.json:4:5]`), err.Error())
+	Equals(t, "alpha", resultMap["alpha"].(testEnvConfig).Name)
+
+	// values are compared once converted, so the same number written differently doesn't conflict
+	os.Setenv("APP_DB_PORT", "05432")
+	_, err = (&ConfigLoader{EnvPrefix: "APP_"}).ReadConfigFiles(&config, "Name", "test/config/env.json")
+	Ok(t, err)
+
+	// self-referential structs are searched once
+	var self testSelfConfig
+	os.Setenv("APP_PORT", "9090")
+	defer os.Unsetenv("APP_PORT")
+	Ok(t, (&ConfigLoader{EnvPrefix: "APP_"}).ReadConfigFile(&self, "test/config/env.json"))
+	Equals(t, 9090, self.Port)
+}
+
+// testSelfConfig A struct holding a pointer to its own type, which walks of config types must not follow forever
+type testSelfConfig struct {
+	Name     string          `json:"name"`
+	Port     int             `json:"port" default:"8080"`
+	Fallback *testSelfConfig `json:"fallback"`
+}
+
+func TestReadConfigFileSelfReferential(t *testing.T) {
+	var config testSelfConfig
+
+	b := []byte(`{"name": "alpha", "fallback": {"name": "beta", "port": 9090, "fallback": {"name": "${fallback.name}2"}}}`)
+	Ok(t, ReadConfigSource(&config, BytesSource("self.json", b)))
+	Equals(t, 8080, config.Port)
+	Equals(t, 9090, config.Fallback.Port)
+	Equals(t, "beta2", config.Fallback.Fallback.Name)
+	Equals(t, 8080, config.Fallback.Fallback.Port)
+	Equals(t, (*testSelfConfig)(nil), config.Fallback.Fallback.Fallback)
+
+	_, provenanceMap, _, _, err := (&ConfigLoader{}).readConfigSources(&config, "Name", []ConfigSource{BytesSource("self.json", b)})
+	Ok(t, err)
+	Equals(t, "self.json", provenanceMap["alpha"]["Fallback.Port"])
+	Equals(t, "default", provenanceMap["alpha"]["Fallback.Fallback.Port"])
+}
+
+type testFlagConfig struct {
+	Name    string        `json:"name"`
+	Port    int           `json:"port" usage:"listen port"`
+	Timeout time.Duration `json:"timeout"`
+	Start   time.Time     `json:"start"`
+	Debug   bool          `json:"debug"`
+	DB      testDBConfig  `json:"db"`
+	Allow   []string      `json:"allow"`
+}
+
+func TestConfigLoaderFlags(t *testing.T) {
+	var config testFlagConfig
+
+	flagSet := NewConfigFlagSet(&config, "test", flag.ContinueOnError)
+	Equals(t, "listen port", flagSet.Lookup("port").Usage)
+	Assert(t, flagSet.Lookup("db.host") != nil, "expected nested flag db.host")
+
+	err := flagSet.Parse([]string{"-port", "9090", "-timeout=1m", "-start", "2020-01-02", "-debug",
+		"-db.host", "db.flag", "-allow", "10.0.0.5", "-allow", "10.0.0.6"})
+	Ok(t, err)
+
+	// flags take precedence over file values, without conflict
+	err = (&ConfigLoader{Flags: flagSet}).ReadConfigFile(&config, "test/config/env.json")
+	Ok(t, err)
+	Equals(t, testFlagConfig{
+		Name:    "alpha",
+		Port:    9090,
+		Timeout: time.Minute,
+		Start:   time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+		Debug:   true,
+		DB:      testDBConfig{Host: "db.flag", Port: 5432},
+		Allow:   []string{"10.0.0.5", "10.0.0.6"},
+	}, config)
+
+	resultMap, err := (&ConfigLoader{Flags: flagSet}).ReadConfigFiles(&config, "Name", "test/config/env.json")
+	Ok(t, err)
+	Equals(t, 9090, resultMap["alpha"].(testFlagConfig).Port)
+
+	// flag values are converted as config file values are
+	flagSet = NewConfigFlagSet(&config, "test", flag.ContinueOnError)
+	Ok(t, flagSet.Parse([]string{"-timeout", "soon"}))
+	err = (&ConfigLoader{Flags: flagSet}).ReadConfigFile(&config, "test/config/env.json")
+	Equals(t, "setting for default invalid, parameter Timeout: duration soon [flag:timeout]", err.Error())
+
+	// a self-referential struct only gets flags for its own fields
+	var self testSelfConfig
+	flagSet = NewConfigFlagSet(&self, "test", flag.ContinueOnError)
+	Ok(t, flagSet.Parse([]string{"-port", "9090"}))
+	Assert(t, flagSet.Lookup("fallback.port") == nil, "unexpected flag fallback.port")
+	Ok(t, (&ConfigLoader{Flags: flagSet}).ReadConfigFile(&self, "test/config/env.json"))
+	Equals(t, 9090, self.Port)
+}
+
+type testDefaultsDBConfig struct {
+	Host string `json:"host" required:"true"`
+	Port int    `json:"port" default:"5432"`
+}
+
+type testDefaultsConfig struct {
+	Name    string                `json:"name"`
+	Port    int                   `json:"port" default:"8080"`
+	Timeout time.Duration         `json:"timeout" default:"30s"`
+	Retries *int                  `json:"retries" default:"3"`
+	Token   string                `json:"token" required:"true"`
+	DB      *testDefaultsDBConfig `json:"db"`
+}
+
+func TestReadConfigFilesDefaults(t *testing.T) {
+	var config testDefaultsConfig
+	retries := 3
+
+	resultMap, err := ReadConfigFiles(&config, "Name", "test/config/defaults.json")
+	Equals(t, "required setting for alpha missing, parameter Token [defaults.json:elem#1]", err.Error())
+
+	// explicit 0 is kept, defaults are parsed like file values
+	Equals(t, testDefaultsConfig{Name: "alpha", Timeout: 30 * time.Second, Retries: &retries}, resultMap["alpha"])
+	Equals(t, testDefaultsConfig{
+		Name:    "beta",
+		Port:    8080,
+		Timeout: 30 * time.Second,
+		Retries: &retries,
+		Token:   "t0ken",
+		DB:      &testDefaultsDBConfig{Host: "db.beta", Port: 5432},
+	}, resultMap["beta"])
+
+	// each file searched is named
+	_, err = ReadConfigFiles(&config, "Name", "test/config/defaults.json", "test/config/env.json")
+	Assert(t, strings.Contains(err.Error(), "required setting for alpha missing, parameter DB.Host ["), err.Error())
+	Assert(t, strings.Contains(err.Error(), "defaults.json:elem#1"), err.Error())
+	Assert(t, strings.Contains(err.Error(), "env.json"), err.Error())
+}
+
+type testValidateConfig struct {
+	Name    string        `json:"name"`
+	Port    int           `json:"port" validate:"min=1,max=65535"`
+	Mode    string        `json:"mode" validate:"oneof=fast|slow"`
+	Email   string        `json:"email" validate:"email"`
+	URL     string        `json:"url" validate:"url"`
+	Code    string        `json:"code" validate:"regex=^[A-Z]{2}-[0-9]{1,3}$"`
+	Timeout time.Duration `json:"timeout" validate:"max=1m"`
+	Tags    []string      `json:"tags" validate:"min=1"`
+	Level   int           `json:"level" validate:"min=1"`
+}
+
+func TestReadConfigFilesValidate(t *testing.T) {
+	var config testValidateConfig
+
+	resultMap, err := ReadConfigFiles(&config, "Name", "test/config/validate.yaml")
+	Assert(t, err != nil, "expected validation errors")
+	Equals(t, 8080, resultMap["alpha"].(testValidateConfig).Port)
+
+	lines := strings.Split(err.Error(), "\n")
+	Equals(t, 8, len(lines))
+	for _, exp := range []string{
+		"setting for beta invalid, parameter Port: value 70000 outside max 65535 [validate.yaml:elem#2]",
+		"setting for beta invalid, parameter Mode: value turbo not one of fast,slow [validate.yaml:elem#2]",
+		"setting for beta invalid, parameter Email: email not-an-email [validate.yaml:elem#2]",
+		"setting for beta invalid, parameter URL: url example.com [validate.yaml:elem#2]",
+		"setting for beta invalid, parameter Code: value ab12 does not match ^[A-Z]{2}-[0-9]{1,3}$ [validate.yaml:elem#2]",
+		"setting for beta invalid, parameter Timeout: value 5m0s outside max 1m [validate.yaml:elem#2]",
+		"setting for beta invalid, parameter Tags: length 0 outside min 1 [validate.yaml:elem#2]",
+	} {
+		Assert(t, strings.Contains(err.Error(), exp), "missing %q in %s", exp, err.Error())
+	}
+}
+
+func TestReadConfigMap(t *testing.T) {
+	// elements from each file are merged by Id
+	configMap, err := ReadConfigMap[testNestedConfig](nil, "Name", "test/config/elements.json", "test/config/env.json")
+	Ok(t, err)
+	Equals(t, 2, len(configMap))
+	Equals(t, testDBConfig{Port: 5432}, configMap["alpha"].DB)
+
+	configMap, err = ReadConfigMap[testNestedConfig](nil, "Name", "test/config/elements.json")
+	Ok(t, err)
+	alpha, beta := configMap["alpha"], configMap["beta"]
+	Equals(t, []string{"10.0.0.1"}, alpha.Allow)
+	Equals(t, []string{"10.0.0.2"}, beta.Allow)
+	Equals(t, map[string]int{"read": 1}, alpha.Quotas)
+	Assert(t, beta.Quotas == nil, "expected no quotas for beta, got %v", beta.Quotas)
+
+	// elements never share pointers
+	*alpha.Retries = 10
+	Equals(t, 2, *beta.Retries)
+
+	config, err := ReadConfig[testNestedConfig](nil, "test/config/nested.json")
+	Ok(t, err)
+	Equals(t, "db.local", config.DB.Host)
+}
+
+func TestConfigLoaderMerge(t *testing.T) {
+	var config testNestedConfig
+	files := []string{"test/config/layers/base.json", "test/config/layers/prod.yaml", "test/config/layers/local.toml"}
+
+	// conflicts are errors by default
+	_, err := ReadConfigFiles(&config, "Name", files...)
+	Assert(t, err != nil, "expected conflict errors")
+
+	loader := &ConfigLoader{Merge: MergeLastWins}
+	resultMap, provenanceMap, err := loader.ReadConfigFilesProvenance(&config, "Name", files...)
+	Ok(t, err)
+	alpha := resultMap["alpha"].(testNestedConfig)
+	Equals(t, testDBConfig{Host: "db.prod", Port: 5432}, alpha.DB)
+	Equals(t, []string{"10.0.0.2"}, alpha.Allow)
+	Equals(t, map[string]int{"write": 20}, alpha.Quotas)
+	Equals(t, 3, *alpha.Retries)
+	Equals(t, map[string]string{
+		"Name":    "local.toml",
+		"DB.Host": "prod.yaml",
+		"DB.Port": "base.json",
+		"Allow":   "prod.yaml",
+		"Quotas":  "prod.yaml",
+		"Retries": "local.toml",
+	}, provenanceMap["alpha"])
+
+	loader = &ConfigLoader{Merge: MergeFirstWins, MergeSlices: MergeAppend, MergeMaps: MergeAppend}
+	resultMap, provenanceMap, err = loader.ReadConfigFilesProvenance(&config, "Name", files...)
+	Ok(t, err)
+	alpha = resultMap["alpha"].(testNestedConfig)
+	Equals(t, testDBConfig{Host: "db.base", Port: 5432}, alpha.DB)
+	Equals(t, []string{"10.0.0.1", "10.0.0.2"}, alpha.Allow)
+	Equals(t, map[string]int{"read": 100, "write": 10}, alpha.Quotas)
+	Equals(t, 1, *alpha.Retries)
+	Equals(t, "base.json,prod.yaml", provenanceMap["alpha"]["Allow"])
+	Equals(t, "base.json", provenanceMap["alpha"]["Retries"])
+
+	// flags take precedence whatever the policy
+	loader.Flags = NewConfigFlagSet(&config, "test", flag.ContinueOnError)
+	Ok(t, loader.Flags.Parse([]string{"-retries", "5", "-allow", "10.0.0.9"}))
+	resultMap, provenanceMap, err = loader.ReadConfigFilesProvenance(&config, "Name", files...)
+	Ok(t, err)
+	alpha = resultMap["alpha"].(testNestedConfig)
+	Equals(t, 5, *alpha.Retries)
+	Equals(t, []string{"10.0.0.9"}, alpha.Allow)
+	Equals(t, "flag:retries", provenanceMap["alpha"]["Retries"])
+}
+
+func TestReadConfigFilesInclude(t *testing.T) {
+	var config testConfig
+
+	// rate.json includes timeout.json, which the glob also matches, so it's only read once
+	_, provenanceMap, err := (&ConfigLoader{}).ReadConfigFilesProvenance(&config, "Name", "test/config/include/app.json")
+	Ok(t, err)
+	Equals(t, testConfig{Name: "alpha", Port: 8080, Timeout: 30 * time.Second, Debug: true, Rate: 1.5}, config)
+	Equals(t, "timeout.json", provenanceMap["alpha"]["Timeout"])
+	Equals(t, "rate.json", provenanceMap["alpha"]["Rate"])
+	Equals(t, "app.json", provenanceMap["alpha"]["Port"])
+
+	// a single file is read over its includes too
+	config = testConfig{}
+	Ok(t, ReadConfigFile(&config, "test/config/include/app.json"))
+	Equals(t, testConfig{Name: "alpha", Port: 8080, Timeout: 30 * time.Second, Debug: true, Rate: 1.5}, config)
+
+	// a file holding nothing but includes reads as the files it includes
+	config = testConfig{}
+	Ok(t, ReadConfigFile(&config, "test/config/include/only.json"))
+	Equals(t, testConfig{Name: "alpha", Port: 8080, Timeout: 30 * time.Second, Debug: true, Rate: 1.5}, config)
+
+	_, err = ReadConfigFiles(&config, "Name", "test/config/include/cycle-a.json")
+	Assert(t, err != nil, "expected include cycle error")
+	Assert(t, strings.Contains(err.Error(), "include cycle"), "unexpected error: %v", err)
+	Assert(t, strings.Contains(err.Error(), "cycle-a.json -> "), "unexpected error: %v", err)
+	err = ReadConfigFile(&config, "test/config/include/cycle-a.json")
+	Assert(t, err != nil && strings.Contains(err.Error(), "include cycle"), "unexpected error: %v", err)
+}
+
+func TestReadConfigPaths(t *testing.T) {
+	var config testConfig
+
+	resultMap, err := ReadConfigPaths(&config, "Name", "test/config/conf.d")
+	Ok(t, err)
+	Equals(t, 2, len(resultMap))
+	Equals(t, 9090, resultMap["beta"].(testConfig).Port)
+
+	Equals(t, []string{"test/config/conf.d/10-alpha.json", "test/config/conf.d/20-beta.yaml"},
+		ExpandConfigPaths("test/config/conf.d/*0-*"))
+
+	_, err = ReadConfigPaths(&config, "Name", "test/config/conf.d/*.toml")
+	Assert(t, err != nil, "expected error for pattern matching no files")
+}
+
+func TestReadConfigFileInterpolate(t *testing.T) {
+	var config testNestedConfig
+
+	os.Setenv("GOUTILS_TEST_DOMAIN", "example.com")
+	defer os.Unsetenv("GOUTILS_TEST_DOMAIN")
+
+	err := ReadConfigFile(&config, "test/config/interpolate.yaml")
+	Assert(t, err != nil, "expected reference errors")
+	Assert(t, strings.Contains(err.Error(), "parameter Backup.Host: reference cycle backup.host -> backup.host [interpolate.yaml:12:3]"),
+		"unexpected error: %v", err)
+	Assert(t, strings.Contains(err.Error(), "parameter Backup.Port: unresolved reference ${missing} [interpolate.yaml:13:3]"),
+		"unexpected error: %v", err)
+
+	Equals(t, "db.alpha.example.com", config.DB.Host)
+	Equals(t, []string{"db.alpha.example.com", "literal ${name}"}, config.Allow)
+	Equals(t, map[string]int{"read": 5432}, config.Quotas)
+	Equals(t, 3, *config.Retries)
+}
+
+func TestReadConfigFilePositions(t *testing.T) {
+	var config testConfig
+
+	err := ReadConfigFile(&config, "test/config/syntax-bad.json")
+	Equals(t, "invalid character ',' looking for beginning of object key string [test/config/syntax-bad.json:3:14]", err.Error())
+
+	// each element of an array of tables has its own positions
+	_, err = ReadConfigFiles(&config, "Name", "test/config/servers-bad.toml")
+	Equals(t, "setting for beta invalid, parameter Port: integer x [servers-bad.toml:7:1]", err.Error())
+
+	// keys holding brackets are keys, not array indexes
+	_, err = ReadConfigSources(&config, "Name", BytesSource("brackets.json", []byte(`{"name": "a", "foo[": 1}`)))
+	Equals(t, "unused setting for a, parameter foo[ [brackets.json:1:15]", err.Error())
+	var nested testNestedConfig
+	err = ReadConfigSource(&nested, BytesSource("brackets.json", []byte(`{"quotas": {"a[b": "x"}}`)))
+	Equals(t, "setting for default invalid, parameter Quotas.a[b: integer x [brackets.json:1:13]", err.Error())
+}
+
+func TestConfigSchema(t *testing.T) {
+	var schema map[string]interface{}
+
+	b, err := ConfigSchema(&testValidateConfig{})
+	Ok(t, err)
+	Ok(t, json.Unmarshal(b, &schema))
+	Equals(t, "testValidateConfig", schema["title"])
+
+	element := schema["$defs"].(map[string]interface{})["element"].(map[string]interface{})
+	properties := element["properties"].(map[string]interface{})
+	Equals(t, false, element["additionalProperties"])
+	Equals(t, map[string]interface{}{
+		"type": []interface{}{"integer", "string"}, "pattern": schemaIntPattern, "minimum": 1.0, "maximum": 65535.0,
+	}, properties["port"])
+	Equals(t, []interface{}{"fast", "slow"}, properties["mode"].(map[string]interface{})["enum"])
+	Equals(t, "email", properties["email"].(map[string]interface{})["format"])
+	Equals(t, "^[A-Z]{2}-[0-9]{1,3}$", properties["code"].(map[string]interface{})["pattern"])
+	Equals(t, map[string]interface{}{"type": "string", "pattern": schemaDurationPattern}, properties["timeout"])
+	Equals(t, 1.0, properties["tags"].(map[string]interface{})["minItems"])
+
+	b, err = ConfigSchema(&testDefaultsConfig{})
+	Ok(t, err)
+	Ok(t, json.Unmarshal(b, &schema))
+	element = schema["$defs"].(map[string]interface{})["element"].(map[string]interface{})
+	properties = element["properties"].(map[string]interface{})
+	Equals(t, []interface{}{map[string]interface{}{"anyOf": []interface{}{
+		map[string]interface{}{"required": []interface{}{"token"}},
+		map[string]interface{}{"required": []interface{}{"Token"}},
+	}}}, element["allOf"])
+	Equals(t, "8080", properties["port"].(map[string]interface{})["default"])
+	Equals(t, "object", properties["db"].(map[string]interface{})["type"])
+
+	// field names are properties too, as the loader accepts them
+	Equals(t, properties["port"], properties["Port"])
+	Equals(t, properties["db"], properties["DB"])
+
+	var n int
+	_, err = ConfigSchema(nil)
+	Assert(t, errors.Is(err, ErrInvalidTarget), "unexpected error: %v", err)
+	_, err = ConfigSchema(&n)
+	Assert(t, errors.Is(err, ErrInvalidTarget), "unexpected error: %v", err)
+	_, err = ConfigSchema(testDefaultsConfig{})
+	Ok(t, err)
+
+	// a struct nested within itself refers to its definition
+	b, err = ConfigSchema(&testSelfConfig{})
+	Ok(t, err)
+	schema = nil
+	Ok(t, json.Unmarshal(b, &schema))
+	defs := schema["$defs"].(map[string]interface{})
+	ref := map[string]interface{}{"$ref": "#/$defs/testSelfConfig"}
+	element = defs["element"].(map[string]interface{})
+	Equals(t, ref, element["properties"].(map[string]interface{})["fallback"])
+	self := defs["testSelfConfig"].(map[string]interface{})["properties"].(map[string]interface{})
+	Equals(t, ref, self["fallback"])
+	Equals(t, nil, self[ConfigIncludeKey])
+}
+
+type testWriteConfig struct {
+	Name    string            `json:"name" usage:"Server name"`
+	Timeout time.Duration     `json:"timeout"`
+	Start   time.Time         `json:"start"`
+	Stamp   time.Time         `json:"stamp"`
+	Rate    float32           `json:"rate"`
+	Big     uint64            `json:"big"`
+	Addr    net.IP            `json:"addr"`
+	DB      testDBConfig      `json:"db" usage:"Database connection"`
+	Backup  *testDBConfig     `json:"backup"`
+	Retries *int              `json:"retries"`
+	Allow   []string          `json:"allow"`
+	Labels  map[string]string `json:"labels"`
+	Servers []testDBConfig    `json:"servers"`
+	Quoted  string            `json:"quoted key"`
+}
+
+func TestWriteConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goutils")
+	Ok(t, err)
+	defer os.RemoveAll(dir)
+
+	retries := 3
+	exp := testWriteConfig{
+		Name:    "alpha",
+		Timeout: 90 * time.Second,
+		Start:   time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC),
+		Stamp:   time.Date(2019, 12, 1, 10, 30, 0, 0, time.UTC),
+		Rate:    0.1,
+		Big:     1<<64 - 1,
+		Addr:    net.ParseIP("10.0.0.1"),
+		DB:      testDBConfig{Host: "db.local", Port: 5432},
+		Backup:  &testDBConfig{Host: "db.backup", Port: 5433},
+		Retries: &retries,
+		Allow:   []string{},
+		Labels:  map[string]string{"env": "prod", "tier": "1"},
+		Servers: []testDBConfig{{Host: "a", Port: 1}, {Host: "b", Port: 2}},
+		Quoted:  "say \"hi\"\n",
+	}
+
+	for _, ext := range []string{"json", "yaml", "toml"} {
+		filename := filepath.Join(dir, "config."+ext)
+		Ok(t, WriteConfigFile(&exp, filename))
+
+		var config testWriteConfig
+		Ok(t, ReadConfigFile(&config, filename))
+		Equals(t, exp, config)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "config.yaml"))
+	Ok(t, err)
+	Assert(t, strings.HasPrefix(string(b), "# Server name\nname: alpha\n"), string(b))
+
+	// nothing is left behind, and types that can't be read back aren't written
+	files, err := ioutil.ReadDir(dir)
+	Ok(t, err)
+	Equals(t, 3, len(files))
+	err = WriteConfigFile(&testNumericConfig{}, filepath.Join(dir, "numeric.json"))
+	Equals(t, "writing config, parameter Level: goutils.testLevel has no MarshalText ["+filepath.Join(dir, "numeric.json")+"]", err.Error())
+
+	err = WriteConfigFile(&exp, filepath.Join(dir, "missing", "config.json"))
+	Assert(t, err != nil, "expected missing directory error")
+}
+
+type testSecretConfig struct {
+	Name     string       `json:"name"`
+	Password string       `json:"password"`
+	DSN      string       `json:"dsn"`
+	DB       testDBConfig `json:"db"`
+}
+
+func TestReadConfigFileSecrets(t *testing.T) {
+	var config testSecretConfig
+
+	loader := &ConfigLoader{Keys: FileKeyProvider{Path: "test/config/secret.key"}}
+	err := loader.ReadConfigFile(&config, "test/config/secrets.yaml")
+	Equals(t, "setting for default invalid, parameter DB.Port: integer (secret) [secrets.yaml:6:3]", err.Error())
+	Equals(t, "hunter2", config.Password)
+	Equals(t, "postgres://app:hunter2@db", config.DSN)
+	Equals(t, "db.secret", config.DB.Host)
+
+	err = ReadConfigFile(&config, "test/config/secrets.yaml")
+	Assert(t, strings.Contains(err.Error(), "parameter Password: no KeyProvider to decrypt secret [secrets.yaml:2:1]"), err.Error())
+
+	// a value encrypted with one key can't be read with another
+	key, err := GenerateConfigKey()
+	Ok(t, err)
+	os.Setenv("GOUTILS_TEST_KEY", key)
+	defer os.Unsetenv("GOUTILS_TEST_KEY")
+	keys := EnvKeyProvider{Name: "GOUTILS_TEST_KEY"}
+	err = (&ConfigLoader{Keys: keys}).ReadConfigFile(&config, "test/config/secrets.yaml")
+	Assert(t, strings.Contains(err.Error(), "parameter Password: secret could not be decrypted, wrong key or corrupted value"), err.Error())
+
+	value, err := EncryptConfigValue("s3cret", keys)
+	Ok(t, err)
+	Assert(t, strings.HasPrefix(value, ConfigSecretPrefix), value)
+	plaintext, err := decryptConfigValue(value, keys)
+	Ok(t, err)
+	Equals(t, "s3cret", plaintext)
+}
+
+type testDumpConfig struct {
+	Name     string        `json:"name"`
+	Password string        `json:"password"`
+	Key      string        `json:"key" secret:"true"`
+	Token    string        `json:"api_token"`
+	Timeout  time.Duration `json:"timeout"`
+	DB       testDBConfig  `json:"db"`
+	Allow    []string      `json:"allow"`
+}
+
+type testTimeConfig struct {
+	Start   time.Time     `json:"start"`
+	Stamp   time.Time     `json:"stamp"`
+	Local   time.Time     `json:"local"`
+	Day     time.Time     `json:"day"`
+	Epoch   time.Time     `json:"epoch"`
+	EpochMs time.Time     `json:"epoch_ms"`
+	Expires time.Time     `json:"expires" layout:"RFC1123"`
+	Delay   time.Duration `json:"delay" unit:"ms"`
+}
+
+func TestReadConfigFileTimes(t *testing.T) {
+	var config testTimeConfig
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	Ok(t, err)
+	loader := &ConfigLoader{Location: berlin}
+	Ok(t, loader.ReadConfigFile(&config, "test/config/times.yaml"))
+
+	at := time.Date(2019, 12, 1, 10, 30, 0, 0, berlin)
+	Assert(t, config.Start.Equal(time.Date(2019, 12, 1, 8, 30, 0, 0, time.UTC)), config.Start.String())
+	Equals(t, 123456789, config.Stamp.Nanosecond())
+	Assert(t, config.Local.Equal(at), config.Local.String())
+	Assert(t, config.Day.Equal(time.Date(2019, 12, 1, 0, 0, 0, 0, berlin)), config.Day.String())
+	Assert(t, config.Epoch.Equal(at), config.Epoch.String())
+	Assert(t, config.EpochMs.Equal(at.Add(123*time.Millisecond)), config.EpochMs.String())
+	Assert(t, config.Expires.Equal(at), config.Expires.String())
+	Equals(t, 1500*time.Millisecond, config.Delay)
+
+	// tagged fields are written back in their layout and unit
+	dir, err := ioutil.TempDir("", "goutils")
+	Ok(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "times.json")
+	Ok(t, WriteConfigFile(&config, filename))
+	b, err := ioutil.ReadFile(filename)
+	Ok(t, err)
+	Assert(t, strings.Contains(string(b), `"delay": 1500`), string(b))
+	Assert(t, strings.Contains(string(b), `"expires": "Sun, 01 Dec 2019 10:30:00 CET"`), string(b))
+
+	var written testTimeConfig
+	Ok(t, loader.ReadConfigFile(&written, filename))
+	Assert(t, written.Stamp.Equal(config.Stamp), written.Stamp.String())
+	Assert(t, written.Expires.Equal(config.Expires), written.Expires.String())
+	Equals(t, config.Delay, written.Delay)
+
+	// without a Location, times without a zone are UTC
+	Ok(t, ReadConfigFile(&config, "test/config/times.yaml"))
+	Equals(t, time.UTC, config.Local.Location())
+}
+
+type testBaseConfig struct {
+	Timeout time.Duration `json:"timeout,omitempty"`
+}
+
+type testEmbeddedConfig struct {
+	testBaseConfig
+	testDBConfig
+	Name     string `json:"name,omitempty"`
+	Internal string `json:"-"`
+}
+
+func TestReadConfigFileEmbedded(t *testing.T) {
+	var config testEmbeddedConfig
+
+	loader := &ConfigLoader{UnknownKeys: UnknownKeysStrict}
+	err := loader.ReadConfigFile(&config, "test/config/embedded.yaml")
+	Assert(t, err != nil, "expected unused keys")
+	Assert(t, strings.Contains(err.Error(), "parameter HOST, did you mean host? [embedded.yaml:2:1]"), err.Error())
+	Assert(t, strings.Contains(err.Error(), "parameter internal [embedded.yaml:5:1]"), err.Error())
+
+	loader.CaseInsensitiveKeys = true
+	err = loader.ReadConfigFile(&config, "test/config/embedded.yaml")
+	Equals(t, "unused setting for default, parameter internal [embedded.yaml:5:1]", err.Error())
+	Equals(t, "alpha", config.Name)
+	Equals(t, "db.local", config.Host)
+	Equals(t, 5432, config.Port)
+	Equals(t, 30*time.Second, config.Timeout)
+	Equals(t, "", config.Internal)
+
+	// promoted fields are written at the top level, empty omitempty fields left out
+	dir, err := ioutil.TempDir("", "goutils")
+	Ok(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "embedded.json")
+	Ok(t, WriteConfigFile(&testEmbeddedConfig{testDBConfig: testDBConfig{Host: "db.local"}}, filename))
+	b, err := ioutil.ReadFile(filename)
+	Ok(t, err)
+	Equals(t, "{\n  \"host\": \"db.local\",\n  \"port\": 0\n}\n", string(b))
+
+	schema, err := ConfigSchema(&config)
+	Ok(t, err)
+	Assert(t, strings.Contains(string(schema), `"timeout"`) && !strings.Contains(string(schema), "Internal"), string(schema))
+}
+
+func TestDumpConfig(t *testing.T) {
+	config := testDumpConfig{
+		Name:     "alpha",
+		Password: "hunter2",
+		Key:      "k3y",
+		Timeout:  30 * time.Second,
+		DB:       testDBConfig{Host: "db.local", Port: 5432},
+		Allow:    []string{"10.0.0.1", "10.0.0.2"},
+	}
+
+	Equals(t, `name      = alpha
+password  = (secret)
+key       = (secret)
+api_token = ""
+timeout   = 30s
+db.host   = db.local
+db.port   = 5432
+allow     = ["10.0.0.1", "10.0.0.2"]
+`, DumpConfig(&config, DumpText))
+
+	dump := DumpConfig(ResultMap{"beta": config, "alpha": config}, DumpJSON)
+	Assert(t, !strings.Contains(dump, "hunter2") && !strings.Contains(dump, "k3y"), dump)
+	var dumped map[string]map[string]interface{}
+	Ok(t, json.Unmarshal([]byte(dump), &dumped))
+	Equals(t, "(secret)", dumped["alpha"]["password"])
+	Equals(t, "30s", dumped["beta"]["timeout"])
+	Assert(t, strings.Index(dump, `"alpha"`) < strings.Index(dump, `"beta"`), dump)
+}
+
+func TestDiff(t *testing.T) {
+	alpha := testDumpConfig{Name: "alpha", Password: "hunter2", Timeout: 30 * time.Second, DB: testDBConfig{Host: "db.local", Port: 5432}}
+	changed := alpha
+	changed.Password = "hunter3"
+	changed.Key = "k3y"
+	changed.DB.Port = 5433
+
+	diff := Diff(
+		ResultMap{"alpha": alpha, "beta": testDumpConfig{Name: "beta"}},
+		ResultMap{"alpha": changed, "gamma": testDumpConfig{Name: "gamma"}},
+	)
+	Equals(t, ConfigDiff{
+		{ElementID: "alpha", Params: []ParamDiff{
+			{Param: "Password", Before: "(secret)", After: "(secret)"},
+			{Param: "Key", Before: "", After: "(secret)"},
+			{Param: "DB.Port", Before: 5432, After: 5433},
+		}},
+		{ElementID: "beta", Removed: true},
+		{ElementID: "gamma", Added: true},
+	}, diff)
+
+	Equals(t, `ELEMENT  CHANGE   PARAMETER  BEFORE    AFTER
+alpha    changed  Password   (secret)  (secret)
+alpha    changed  Key        ""        (secret)
+alpha    changed  DB.Port    5432      5433
+beta     removed  -          -         -
+gamma    added    -          -         -
+`, diff.String())
+	Equals(t, "no changes\n", Diff(ResultMap{"alpha": alpha}, ResultMap{"alpha": alpha}).String())
+
+	// pointers are compared as the elements they point to
+	diff = Diff(ResultMap{"alpha": &alpha}, ResultMap{"alpha": &changed})
+	Equals(t, 3, len(diff[0].Params))
+	Equals(t, ParamDiff{Param: "Password", Before: "(secret)", After: "(secret)"}, diff[0].Params[0])
+	Assert(t, !strings.Contains(diff.String(), "hunter"), diff.String())
+
+	// a nil element has no parameters set
+	diff = Diff(ResultMap{"alpha": nil}, ResultMap{"alpha": &testDumpConfig{Name: "alpha", Password: "hunter2"}})
+	Equals(t, ConfigDiff{{ElementID: "alpha", Params: []ParamDiff{
+		{Param: "Name", Before: "", After: "alpha"},
+		{Param: "Password", Before: "", After: "(secret)"},
+	}}}, diff)
+	Equals(t, "no changes\n", Diff(ResultMap{"alpha": nil}, ResultMap{"alpha": (*testDumpConfig)(nil)}).String())
+
+	// a struct nested within its own type is compared as one parameter, masked
+	self := testSelfConfig{Name: "alpha", Fallback: &testSelfConfig{Name: "beta"}}
+	selfChanged := testSelfConfig{Name: "alpha", Fallback: &testSelfConfig{Name: "gamma"}}
+	Equals(t, []string{"Name", "Port", "Fallback"}, paramPaths(reflect.TypeOf(self), ""))
+	Equals(t, ConfigDiff{{ElementID: "alpha", Params: []ParamDiff{{Param: "Fallback", Before: "(secret)", After: "(secret)"}}}},
+		Diff(ResultMap{"alpha": self}, ResultMap{"alpha": selfChanged}))
+
+	// elements of different types are masked as a whole
+	diff = Diff(ResultMap{"alpha": alpha}, ResultMap{"alpha": "hunter2"})
+	Equals(t, ConfigDiff{{ElementID: "alpha", Params: []ParamDiff{{Before: "(secret)", After: "(secret)"}}}}, diff)
+}
@@ -0,0 +1,122 @@
+package goutils
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// configTimeLayouts Layouts tried in order for times without a `layout:"..."` tag
+// - RFC3339Nano also reads RFC3339, with or without fractional seconds, with Z or an offset
+// - The others have no zone, so are read in ConfigLoader.Location
+var configTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// configLayoutNames Names for `layout:"..."` tags, for the layouts the time package defines
+var configLayoutNames = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"UnixDate":    time.UnixDate,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC850":      time.RFC850,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"DateTime":    "2006-01-02 15:04:05",
+	"DateOnly":    "2006-01-02",
+	"TimeOnly":    "15:04:05",
+}
+
+// configUnits Units for `unit:"..."` tags, of durations and epoch times given as integers
+var configUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"µs": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+}
+
+// epochMillisFrom Integer times at least this large are epoch milliseconds rather than seconds, unless tagged with a unit
+// - As seconds, this would be after the year 5000
+const epochMillisFrom = 100000000000
+
+// location Zone for times given without one
+func (loader *ConfigLoader) location() *time.Location {
+	if loader.Location == nil {
+		return time.UTC
+	}
+	return loader.Location
+}
+
+// parseTime Parse config value s into a time, for a field with struct tag
+// - With a `layout:"..."` tag, s must match that layout, given as a Go layout or a name such as RFC1123
+// - Integers are epoch times, in the `unit:"..."` tag's unit, or seconds or milliseconds by size
+// - Otherwise RFC3339 with optional fractional seconds, or a date or date-time without zone, see configTimeLayouts
+// - Times without a zone are in the loader's Location
+func (loader *ConfigLoader) parseTime(s string, tag reflect.StructTag) (date time.Time, err error) {
+	var n int64
+
+	loc := loader.location()
+	if layout, ok := tag.Lookup("layout"); ok {
+		if named, isNamed := configLayoutNames[layout]; isNamed {
+			layout = named
+		}
+		return time.ParseInLocation(layout, s, loc)
+	}
+
+	if n, err = strconv.ParseInt(s, 10, 64); err == nil {
+		return epochTime(n, tag.Get("unit"), loc)
+	}
+	for _, layout := range configTimeLayouts {
+		if date, err = time.ParseInLocation(layout, s, loc); err == nil {
+			return
+		}
+	}
+	return
+}
+
+// epochTime Time n units after the Unix epoch, in loc
+func epochTime(n int64, unit string, loc *time.Location) (date time.Time, err error) {
+	if unit == "" {
+		unit = "s"
+		if n >= epochMillisFrom || n <= -epochMillisFrom {
+			unit = "ms"
+		}
+	}
+	size, ok := configUnits[unit]
+	if !ok {
+		return date, fmt.Errorf("unknown unit %s", unit)
+	}
+	seconds := size / time.Second
+	if seconds > 0 {
+		return time.Unix(n*int64(seconds), 0).In(loc), nil
+	}
+	return time.Unix(0, n*int64(size)).In(loc), nil
+}
+
+// parseDuration Parse config value s into a duration, for a field with struct tag
+// - With a `unit:"..."` tag, an integer is a number of that unit, e.g. 1500 with unit:"ms"
+// - Otherwise s is read by time.ParseDuration, e.g. 1m30s
+func parseDuration(s string, tag reflect.StructTag) (dur time.Duration, err error) {
+	var n int64
+
+	if unit, ok := tag.Lookup("unit"); ok {
+		if n, err = strconv.ParseInt(s, 10, 64); err == nil {
+			size, known := configUnits[unit]
+			if !known {
+				return 0, fmt.Errorf("unknown unit %s", unit)
+			}
+			return time.Duration(n) * size, nil
+		}
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,173 @@
+package goutils
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// validateRules Check data object (st, sv) fields against rules in their `validate:"..."` tag
+// - Rules are comma separated: min=N, max=N, oneof=a|b|c, email, url, regex=PATTERN
+// - regex must be the last rule, since its pattern may itself contain commas
+// - min and max compare numbers and durations by value, strings, slices and maps by length
+// - Only parameters given a value by a source or default are checked, nested structs recursively
+func (ctx *parseContext) validateRules(st reflect.Type, sv reflect.Value, prefix string) {
+	var param, rules, filename string
+	var ok bool
+
+	for _, field := range configFields(st) {
+		param = prefix + field.Name
+		fv, _ := fieldValue(sv, field, false)
+
+		filename, ok = ctx.set[param]
+		if !ok {
+			continue
+		}
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+
+		rules, ok = field.Tag.Lookup("validate")
+		if ok {
+			for _, problem := range checkRules(fv, rules) {
+				if ctx.secrets[param] {
+					problem = redactSecret(problem, fv)
+				}
+				ctx.errList.Addf("setting for %s invalid, parameter %s: %s [%s]",
+					ctx.elementID, param, problem, filename)
+			}
+		}
+
+		if isNested(fv.Type()) {
+			ctx.validateRules(fv.Type(), fv, param+".")
+		}
+	}
+}
+
+// validateRule One rule of a `validate:"..."` tag, e.g. name max with arg 10
+type validateRule struct {
+	rule string
+	name string
+	arg  string
+}
+
+// splitRules Split a `validate:"..."` tag into its comma separated rules
+// - regex takes the rest of the tag as its pattern
+func splitRules(rules string) (split []validateRule) {
+	var rule, name, arg string
+
+	for len(rules) > 0 {
+		if strings.HasPrefix(rules, "regex=") {
+			rule, rules = rules, ""
+		} else if i := strings.Index(rules, ","); i >= 0 {
+			rule, rules = rules[:i], rules[i+1:]
+		} else {
+			rule, rules = rules, ""
+		}
+		rule = strings.TrimSpace(rule)
+		if len(rule) == 0 {
+			continue
+		}
+
+		name, arg = rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, arg = rule[:i], rule[i+1:]
+		}
+		split = append(split, validateRule{rule: rule, name: name, arg: arg})
+	}
+	return
+}
+
+// checkRules Check value fv against comma separated validation rules, returning a description of each failure
+func checkRules(fv reflect.Value, rules string) (problems []string) {
+	var rule, name, arg, text string
+	var err error
+
+	text = fmt.Sprintf("%v", fv.Interface())
+
+	for _, split := range splitRules(rules) {
+		rule, name, arg = split.rule, split.name, split.arg
+
+		switch name {
+		case "min", "max":
+			err = checkBound(fv, name, arg)
+			if err != nil {
+				problems = append(problems, err.Error())
+			}
+		case "oneof":
+			if !StringArrayContains(strings.Split(arg, "|"), text) {
+				problems = append(problems, fmt.Sprintf("value %s not one of %s", text, strings.Replace(arg, "|", ",", -1)))
+			}
+		case "email":
+			if ValidateEmailAddress(text) != nil {
+				problems = append(problems, fmt.Sprintf("email %s", text))
+			}
+		case "url":
+			u, err := url.ParseRequestURI(text)
+			if err != nil || len(u.Scheme) == 0 || len(u.Host) == 0 {
+				problems = append(problems, fmt.Sprintf("url %s", text))
+			}
+		case "regex":
+			re, err := regexp.Compile(arg)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("invalid rule regex=%s", arg))
+			} else if !re.MatchString(text) {
+				problems = append(problems, fmt.Sprintf("value %s does not match %s", text, arg))
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("unknown rule %s", rule))
+		}
+	}
+	return
+}
+
+// checkBound Check value fv against a min or max bound
+// - Numbers and durations are compared by value, strings, slices and maps by length
+func checkBound(fv reflect.Value, name, arg string) (err error) {
+	var bound, value float64
+	var length, limit int
+	var dur time.Duration
+
+	switch fv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		limit, err = strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("invalid rule %s=%s", name, arg)
+		}
+		length = fv.Len()
+		if (name == "min" && length < limit) || (name == "max" && length > limit) {
+			return fmt.Errorf("length %d outside %s %d", length, name, limit)
+		}
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value = float64(fv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		value = float64(fv.Uint())
+	case reflect.Float32, reflect.Float64:
+		value = fv.Float()
+	default:
+		return fmt.Errorf("rule %s not supported for type %s", name, fv.Type())
+	}
+
+	if fv.Type() == durationType {
+		dur, err = time.ParseDuration(arg)
+		bound = float64(dur)
+	} else {
+		bound, err = strconv.ParseFloat(arg, 64)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid rule %s=%s", name, arg)
+	}
+
+	if (name == "min" && value < bound) || (name == "max" && value > bound) {
+		return fmt.Errorf("value %v outside %s %s", fv.Interface(), name, arg)
+	}
+	return nil
+}
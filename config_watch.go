@@ -0,0 +1,261 @@
+package goutils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/AndrewDonelson/golog"
+	"github.com/fsnotify/fsnotify"
+)
+
+// ConfigReload Outcome of reloading config files after a change, passed to the watcher's callback
+// - ResultMap is the config now in effect, which is the previous one if the reload failed
+// - Diff lists what changed per element, it's empty if the reload failed
+// - Err holds the compiled ErrList of a failed reload
+type ConfigReload struct {
+	ResultMap ResultMap
+	Diff      ConfigDiff
+	Err       error
+}
+
+// ConfigWatcher Re-reads config files whenever one changes, see WatchConfigFiles
+type ConfigWatcher struct {
+	loader    *ConfigLoader
+	template  reflect.Value
+	idName    string
+	filenames []string
+	onReload  func(ConfigReload)
+	interval  time.Duration
+
+	mutex     sync.RWMutex
+	resultMap ResultMap
+	files     []string
+	stats     map[string]os.FileInfo
+	notify    *fsnotify.Watcher
+	dirs      map[string]bool
+	done      chan struct{}
+	stopped   sync.WaitGroup
+}
+
+// WatchConfigFiles Read config files as ReadConfigFiles, then re-read them each time one changes
+// - Changes are found with inotify (or the platform equivalent), falling back to polling every interval
+// - With inotify, interval is how long changes are gathered before reloading, so an editor's save reloads once
+// - Interval is DefaultConfigPollInterval if 0 or less
+// - Files they include are watched too, and the included set is listed again after each reload
+// - onReload is called after each reload with the new ResultMap and a diff per element
+// - A reload that fails keeps the previous config, and reports the error instead
+// - 'data' is only set by the initial read, each reload starts from a copy of its value at that time
+func (loader *ConfigLoader) WatchConfigFiles(data interface{}, idName string, interval time.Duration, onReload func(ConfigReload), filenames ...string) (watcher *ConfigWatcher, err error) {
+	var sv reflect.Value
+
+	if _, sv, err = configTarget("WatchConfigFiles", data); err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		interval = DefaultConfigPollInterval
+	}
+	watcher = &ConfigWatcher{
+		loader:    loader,
+		template:  copyValue(sv),
+		idName:    idName,
+		filenames: filenames,
+		onReload:  onReload,
+		interval:  interval,
+		dirs:      make(map[string]bool),
+		done:      make(chan struct{}),
+	}
+
+	watcher.resultMap, err = loader.ReadConfigFiles(data, idName, filenames...)
+	if err != nil {
+		return nil, err
+	}
+	watcher.files, _ = includedFiles(filenames...)
+	watcher.stats = statFiles(watcher.files)
+
+	watcher.notify, err = fsnotify.NewWatcher()
+	if err == nil {
+		err = watcher.watchDirs(watcher.files)
+	}
+	if err != nil {
+		golog.Log.Warningf("Watching config files, polling every %v instead: %v", interval, err)
+		if watcher.notify != nil {
+			watcher.notify.Close()
+			watcher.notify = nil
+		}
+		err = nil
+	}
+
+	watcher.stopped.Add(1)
+	go watcher.run()
+	return
+}
+
+// ResultMap Config currently in effect
+func (watcher *ConfigWatcher) ResultMap() ResultMap {
+	watcher.mutex.RLock()
+	defer watcher.mutex.RUnlock()
+	return watcher.resultMap
+}
+
+// Close Stop watching config files
+func (watcher *ConfigWatcher) Close() (err error) {
+	close(watcher.done)
+	watcher.stopped.Wait()
+	if watcher.notify != nil {
+		err = watcher.notify.Close()
+	}
+	return
+}
+
+// run Wait for file changes until closed, reloading at most once each interval
+func (watcher *ConfigWatcher) run() {
+	var events <-chan fsnotify.Event
+	var errors <-chan error
+	var changed bool
+
+	defer watcher.stopped.Done()
+
+	if watcher.notify != nil {
+		events = watcher.notify.Events
+		errors = watcher.notify.Errors
+	}
+	ticker := time.NewTicker(watcher.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-watcher.done:
+			return
+		case event := <-events:
+			if watcher.watching(event.Name) {
+				changed = true
+			}
+		case err := <-errors:
+			golog.Log.Warningf("Watching config files: %v", err)
+		case <-ticker.C:
+			stats := statFiles(watcher.watchedFiles())
+			if watcher.notify == nil {
+				changed = !sameStats(watcher.stats, stats)
+			}
+			watcher.stats = stats
+			if changed {
+				changed = false
+				watcher.Reload()
+			}
+		}
+	}
+}
+
+// Reload Re-read the config files now, calling onReload with the outcome
+func (watcher *ConfigWatcher) Reload() {
+	var reload ConfigReload
+	var resultMap ResultMap
+	var err error
+
+	data := reflect.New(watcher.template.Type())
+	data.Elem().Set(copyValue(watcher.template))
+
+	resultMap, err = watcher.loader.ReadConfigFiles(data.Interface(), watcher.idName, watcher.filenames...)
+
+	// Includes may have been added or removed
+	files, _ := includedFiles(watcher.filenames...)
+
+	watcher.mutex.Lock()
+	watcher.files = files
+	if watcher.notify != nil {
+		if dirErr := watcher.watchDirs(files); dirErr != nil {
+			golog.Log.Warningf("Watching config files: %v", dirErr)
+		}
+	}
+	if err != nil {
+		golog.Log.Warningf("Reloading config files, keeping previous config: %v", err)
+		reload = ConfigReload{ResultMap: watcher.resultMap, Err: err}
+	} else {
+		reload = ConfigReload{ResultMap: resultMap, Diff: Diff(watcher.resultMap, resultMap)}
+		watcher.resultMap = resultMap
+		golog.Log.Debugf("Reloaded config files, %d elements changed", len(reload.Diff))
+	}
+	watcher.mutex.Unlock()
+
+	if watcher.onReload != nil {
+		watcher.onReload(reload)
+	}
+}
+
+// watchDirs Watch the directory of each file not already watched, so files replaced by rename are still seen
+func (watcher *ConfigWatcher) watchDirs(files []string) (err error) {
+	var dir string
+
+	for _, filename := range files {
+		dir = filepath.Dir(filename)
+		if !watcher.dirs[dir] {
+			if err = watcher.notify.Add(dir); err != nil {
+				return
+			}
+			watcher.dirs[dir] = true
+		}
+	}
+	return
+}
+
+// watchedFiles Config files and the files they include, as last read
+func (watcher *ConfigWatcher) watchedFiles() []string {
+	watcher.mutex.RLock()
+	defer watcher.mutex.RUnlock()
+	return watcher.files
+}
+
+// watching Is name one of the watched config files, or a file they include
+func (watcher *ConfigWatcher) watching(name string) bool {
+	var fullpath string
+	var err error
+
+	name = filepath.Clean(name)
+	for _, filename := range watcher.watchedFiles() {
+		fullpath, err = filepath.Abs(filename)
+		if err != nil {
+			continue
+		}
+		if name == filepath.Clean(filename) || name == fullpath {
+			return true
+		}
+	}
+	return false
+}
+
+// statFiles Get file info for each file that exists, to detect changes by polling
+func statFiles(filenames []string) (stats map[string]os.FileInfo) {
+	stats = make(map[string]os.FileInfo)
+	for _, filename := range filenames {
+		if fileInfo, err := os.Stat(filename); err == nil {
+			stats[filename] = fileInfo
+		}
+	}
+	return
+}
+
+// sameStats Are files unchanged in size and modification time, and none created or removed
+func sameStats(oldStats, newStats map[string]os.FileInfo) bool {
+	if len(oldStats) != len(newStats) {
+		return false
+	}
+	for filename, oldInfo := range oldStats {
+		newInfo, ok := newStats[filename]
+		if !ok || newInfo.Size() != oldInfo.Size() || !newInfo.ModTime().Equal(oldInfo.ModTime()) {
+			return false
+		}
+	}
+	return true
+}
+
+// String Describe the reload outcome for logging
+func (reload ConfigReload) String() string {
+	if reload.Err != nil {
+		return fmt.Sprintf("config reload failed, keeping previous config: %v", reload.Err)
+	}
+	return fmt.Sprintf("config reloaded, %d elements changed", len(reload.Diff))
+}
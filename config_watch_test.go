@@ -0,0 +1,97 @@
+package goutils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchConfigFiles(t *testing.T) {
+	var config testConfig
+
+	dir, err := ioutil.TempDir("", "goutils")
+	Ok(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "servers.json")
+	Ok(t, ioutil.WriteFile(filename, []byte(`[{"name": "alpha", "port": 8080}]`), 0644))
+
+	reloads := make(chan ConfigReload, 10)
+	watcher, err := (&ConfigLoader{}).WatchConfigFiles(&config, "Name", 50*time.Millisecond,
+		func(reload ConfigReload) { reloads <- reload }, filename)
+	Ok(t, err)
+	defer watcher.Close()
+	Equals(t, 8080, watcher.ResultMap()["alpha"].(testConfig).Port)
+
+	// changed port and added element are reported
+	replaceFile(t, filename, `[{"name": "alpha", "port": 9090}, {"name": "beta"}]`)
+	reload := waitReload(t, reloads)
+	Ok(t, reload.Err)
+	Equals(t, ConfigDiff{
+		{ElementID: "alpha", Params: []ParamDiff{{Param: "Port", Before: 8080, After: 9090}}},
+		{ElementID: "beta", Added: true},
+	}, reload.Diff)
+	Equals(t, 9090, watcher.ResultMap()["alpha"].(testConfig).Port)
+
+	// failed reload keeps the previous config
+	replaceFile(t, filename, `[{"name": "alpha", "port": "ninety"}]`)
+	reload = waitReload(t, reloads)
+	Equals(t, "setting for alpha invalid, parameter Port: integer ninety [servers.json:1:20]", reload.Err.Error())
+	Equals(t, 2, len(reload.ResultMap))
+	Equals(t, 9090, watcher.ResultMap()["alpha"].(testConfig).Port)
+}
+
+func TestWatchConfigFilesInclude(t *testing.T) {
+	var config testConfig
+
+	dir, err := ioutil.TempDir("", "goutils")
+	Ok(t, err)
+	defer os.RemoveAll(dir)
+	Ok(t, os.Mkdir(filepath.Join(dir, "common"), 0755))
+	filename := filepath.Join(dir, "app.json")
+	included := filepath.Join(dir, "common", "timeout.json")
+	Ok(t, ioutil.WriteFile(included, []byte(`{"name": "alpha", "timeout": "30s"}`), 0644))
+	Ok(t, ioutil.WriteFile(filename, []byte(`{"$include": "common/*.json", "name": "alpha", "port": 8080}`), 0644))
+
+	reloads := make(chan ConfigReload, 10)
+	watcher, err := (&ConfigLoader{}).WatchConfigFiles(&config, "Name", 50*time.Millisecond,
+		func(reload ConfigReload) { reloads <- reload }, filename)
+	Ok(t, err)
+	defer watcher.Close()
+	Equals(t, 30*time.Second, watcher.ResultMap()["alpha"].(testConfig).Timeout)
+
+	// editing an included file reloads
+	replaceFile(t, included, `{"name": "alpha", "timeout": "1m"}`)
+	reload := waitReload(t, reloads)
+	Ok(t, reload.Err)
+	Equals(t, ConfigDiff{
+		{ElementID: "alpha", Params: []ParamDiff{{Param: "Timeout", Before: 30 * time.Second, After: time.Minute}}},
+	}, reload.Diff)
+}
+
+func TestWatchConfigFilesDefaultInterval(t *testing.T) {
+	var config testConfig
+
+	// 0 interval must not panic the watching goroutine
+	watcher, err := (&ConfigLoader{}).WatchConfigFiles(&config, "Name", 0, nil, "test/config/servers.yaml")
+	Ok(t, err)
+	Equals(t, DefaultConfigPollInterval, watcher.interval)
+	time.Sleep(10 * time.Millisecond)
+	Ok(t, watcher.Close())
+}
+
+func waitReload(t *testing.T, reloads chan ConfigReload) (reload ConfigReload) {
+	select {
+	case reload = <-reloads:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for config reload")
+	}
+	return
+}
+
+// replaceFile Write new contents by rename, as editors do, so a reload never sees a partly written file
+func replaceFile(t *testing.T, filename, contents string) {
+	Ok(t, ioutil.WriteFile(filename+".tmp", []byte(contents), 0644))
+	Ok(t, os.Rename(filename+".tmp", filename))
+}
@@ -0,0 +1,486 @@
+package goutils
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configEntry One key of a config element to write, in struct field order
+// - value is nil, a string, bool, configNumber, []interface{} for arrays, or []configEntry for nested elements and maps
+// - comment is the field's `usage:"..."` tag, written above the key in YAML and TOML
+type configEntry struct {
+	key     string
+	comment string
+	value   interface{}
+}
+
+// configEncoder Converts config structs to entries, for WriteConfigFile and DumpConfig
+// - If redact, secret fields that are set are masked, see isSecretField, and values that can't be read back are shown with %v
+type configEncoder struct {
+	redact bool
+}
+
+// configNumber Number to write as it is, without quotes
+type configNumber string
+
+// maxExactInt Largest integer a float64 holds exactly, larger integers are written as strings so they read back exactly
+const maxExactInt = 1 << 53
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// WriteConfigFile Write the struct 'data' points to as a config file that ReadConfigFile reads back identically
+// - Format is chosen from the filename extension, see ConfigFormat
+// - Keys are named by json tag, or field name if untagged, in struct field order, map keys sorted
+// - Durations are written as "30s", times as "2006-01-02" if midnight UTC, otherwise RFC3339 with any fractional seconds
+// - Times and durations with layout or unit tags are written in that layout or unit, see parseTime and parseDuration
+// - Types implementing encoding.TextUnmarshaler must also implement encoding.TextMarshaler
+// - YAML and TOML files are annotated with each field's `usage:"..."` tag as a comment
+// - Nil slices and maps are left out, as are nil pointers in TOML, which has no null, and empty fields tagged omitempty
+// - Fields of embedded structs are written at the level of the struct embedding them, see configFields
+// - The file is replaced atomically, by writing a temporary file in the same directory then renaming it
+func WriteConfigFile(data interface{}, filename string) (err error) {
+	var entries []configEntry
+	var b []byte
+	var fullpath string
+
+	fullpath, err = ValidateFileOrParentDir(filename)
+	if err != nil {
+		return
+	}
+
+	sv := reflect.ValueOf(data)
+	for sv.Kind() == reflect.Ptr {
+		sv = sv.Elem()
+	}
+	if sv.Kind() != reflect.Struct {
+		return fmt.Errorf("WriteConfigFile: 'data' must be a struct, not %s [%s]", sv.Kind(), filename)
+	}
+	entries, err = configEncoder{}.encodeStruct(sv, "")
+	if err != nil {
+		return fmt.Errorf("%v [%s]", err, filename)
+	}
+
+	switch ConfigFormat(filename) {
+	case ConfigFormatYAML:
+		b, err = yaml.Marshal(yamlNode(entries))
+		if err != nil {
+			return fmt.Errorf("%v [%s]", err, filename)
+		}
+	case ConfigFormatTOML:
+		var sb strings.Builder
+		writeTOML(&sb, "", entries)
+		b = []byte(sb.String())
+	default:
+		var sb strings.Builder
+		writeJSON(&sb, entries, "")
+		sb.WriteString("\n")
+		b = []byte(sb.String())
+	}
+
+	return writeFileAtomic(fullpath, b)
+}
+
+// writeFileAtomic Replace file with contents b, keeping its permissions if it exists
+func writeFileAtomic(fullpath string, b []byte) (err error) {
+	var tmp *os.File
+
+	mode := os.FileMode(0644)
+	if fileInfo, statErr := os.Stat(fullpath); statErr == nil {
+		mode = fileInfo.Mode().Perm()
+	}
+
+	tmp, err = ioutil.TempFile(filepath.Dir(fullpath), "."+filepath.Base(fullpath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("writing file: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(b); err != nil {
+		return fmt.Errorf("writing file: %v", err)
+	}
+	if err = tmp.Sync(); err != nil {
+		return fmt.Errorf("writing file: %v", err)
+	}
+	if err = tmp.Chmod(mode); err != nil {
+		return fmt.Errorf("writing file: %v", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("writing file: %v", err)
+	}
+	if err = os.Rename(tmp.Name(), fullpath); err != nil {
+		return fmt.Errorf("writing file: %v", err)
+	}
+	return nil
+}
+
+// encodeStruct List the exported fields of struct value sv as config entries, where param is the path to sv
+func (enc configEncoder) encodeStruct(sv reflect.Value, param string) (entries []configEntry, err error) {
+	var value interface{}
+	var omit bool
+
+	for _, field := range configFields(sv.Type()) {
+		fv, ok := fieldValue(sv, field, false)
+		if !ok || field.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		if enc.redact && isSecretField(field.StructField) && !fv.IsZero() {
+			value, omit = secretMask, false
+		} else if value, omit, err = enc.encodeValue(fv, param+field.Name, field.Tag); err != nil {
+			return
+		}
+		if !omit {
+			entries = append(entries, configEntry{key: field.key, comment: field.Tag.Get("usage"), value: value})
+		}
+	}
+	return
+}
+
+// isEmptyValue Is fv empty for the json omitempty option, false, zero, or a nil pointer or empty string, slice or map
+func isEmptyValue(fv reflect.Value) bool {
+	switch fv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return fv.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Interface, reflect.Ptr:
+		return fv.IsZero()
+	default:
+		return false
+	}
+}
+
+// encodeValue Convert field fv to the representation parseValue reads, omit is true for nil slices and maps
+// - tag is the field's struct tag, whose layout and unit tags format times and durations as parseTime and parseDuration read them
+func (enc configEncoder) encodeValue(fv reflect.Value, param string, tag reflect.StructTag) (value interface{}, omit bool, err error) {
+	var text []byte
+	var entries []configEntry
+	var i int
+
+	switch {
+	case fv.Type() == durationType:
+		return encodeDuration(fv.Interface().(time.Duration), tag), false, nil
+	case fv.Type() == timeType:
+		return encodeTime(fv.Interface().(time.Time), tag), false, nil
+	case fv.Kind() != reflect.Ptr && reflect.PtrTo(fv.Type()).Implements(textUnmarshalerType):
+		if !fv.Type().Implements(textMarshalerType) {
+			if enc.redact {
+				return fmt.Sprintf("%v", fv.Interface()), false, nil
+			}
+			return nil, false, fmt.Errorf("writing config, parameter %s: %s has no MarshalText", param, fv.Type())
+		}
+		text, err = fv.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, false, fmt.Errorf("writing config, parameter %s: %v", param, err)
+		}
+		return string(text), false, nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		return fv.String(), false, nil
+	case reflect.Bool:
+		return fv.Bool(), false, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if fv.Int() > maxExactInt || fv.Int() < -maxExactInt {
+			return strconv.FormatInt(fv.Int(), 10), false, nil
+		}
+		return configNumber(strconv.FormatInt(fv.Int(), 10)), false, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if fv.Uint() > maxExactInt {
+			return strconv.FormatUint(fv.Uint(), 10), false, nil
+		}
+		return configNumber(strconv.FormatUint(fv.Uint(), 10)), false, nil
+	case reflect.Float32, reflect.Float64:
+		f := fv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return strconv.FormatFloat(f, 'g', -1, fv.Type().Bits()), false, nil
+		}
+		return configNumber(strconv.FormatFloat(f, 'g', -1, fv.Type().Bits())), false, nil
+	case reflect.Interface:
+		if fv.IsNil() {
+			return nil, false, nil
+		}
+		return fv.Interface(), false, nil
+	case reflect.Ptr:
+		if fv.IsNil() {
+			return nil, false, nil
+		}
+		return enc.encodeValue(fv.Elem(), param, tag)
+	case reflect.Struct:
+		entries, err = enc.encodeStruct(fv, param+".")
+		return entries, false, err
+	case reflect.Slice:
+		if fv.IsNil() {
+			return nil, true, nil
+		}
+		arr := make([]interface{}, fv.Len())
+		for i = range arr {
+			if arr[i], _, err = enc.encodeValue(fv.Index(i), fmt.Sprintf("%s[%d]", param, i), tag); err != nil {
+				return
+			}
+		}
+		return arr, false, nil
+	case reflect.Map:
+		if fv.IsNil() {
+			return nil, true, nil
+		}
+		for _, key := range fv.MapKeys() {
+			keyText := fmt.Sprintf("%v", key.Interface())
+			if value, _, err = enc.encodeValue(fv.MapIndex(key), param+"."+keyText, tag); err != nil {
+				return
+			}
+			entries = append(entries, configEntry{key: keyText, value: value})
+		}
+		sort.Slice(entries, func(a, b int) bool { return entries[a].key < entries[b].key })
+		if entries == nil {
+			entries = []configEntry{}
+		}
+		return entries, false, nil
+	default:
+		if enc.redact {
+			return fmt.Sprintf("%v", fv.Interface()), false, nil
+		}
+		return nil, false, fmt.Errorf("writing config, parameter %s: unsupported type %s", param, fv.Type())
+	}
+}
+
+// encodeTime Format date as parseTime reads it for a field with struct tag
+func encodeTime(date time.Time, tag reflect.StructTag) interface{} {
+	if layout, ok := tag.Lookup("layout"); ok {
+		if named, isNamed := configLayoutNames[layout]; isNamed {
+			layout = named
+		}
+		return date.Format(layout)
+	}
+	if unit, ok := tag.Lookup("unit"); ok {
+		if size, known := configUnits[unit]; known {
+			return configNumber(strconv.FormatInt(date.UnixNano()/int64(size), 10))
+		}
+	}
+	if date.Location() == time.UTC && date.Equal(date.Truncate(24*time.Hour)) {
+		return date.Format("2006-01-02")
+	}
+	return date.Format(time.RFC3339Nano)
+}
+
+// encodeDuration Format dur as parseDuration reads it, a whole number of the unit tag's unit where possible
+func encodeDuration(dur time.Duration, tag reflect.StructTag) interface{} {
+	if unit, ok := tag.Lookup("unit"); ok {
+		if size, known := configUnits[unit]; known && dur%size == 0 {
+			return configNumber(strconv.FormatInt(int64(dur/size), 10))
+		}
+	}
+	return dur.String()
+}
+
+// jsonString Quote s as a JSON string, which is also a valid TOML basic string
+func jsonString(s string) string {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// writeJSON Write value as indented JSON
+func writeJSON(sb *strings.Builder, value interface{}, indent string) {
+	switch v := value.(type) {
+	case nil:
+		sb.WriteString("null")
+	case configNumber:
+		sb.WriteString(string(v))
+	case string:
+		sb.WriteString(jsonString(v))
+	case bool:
+		sb.WriteString(strconv.FormatBool(v))
+	case []interface{}:
+		if len(v) == 0 {
+			sb.WriteString("[]")
+			return
+		}
+		sb.WriteString("[\n")
+		for i, item := range v {
+			sb.WriteString(indent + "  ")
+			writeJSON(sb, item, indent+"  ")
+			if i < len(v)-1 {
+				sb.WriteString(",")
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString(indent + "]")
+	case []configEntry:
+		if len(v) == 0 {
+			sb.WriteString("{}")
+			return
+		}
+		sb.WriteString("{\n")
+		for i, entry := range v {
+			sb.WriteString(indent + "  " + jsonString(entry.key) + ": ")
+			writeJSON(sb, entry.value, indent+"  ")
+			if i < len(v)-1 {
+				sb.WriteString(",")
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString(indent + "}")
+	default:
+		b, _ := json.Marshal(v)
+		sb.Write(b)
+	}
+}
+
+// yamlNode Convert value to a YAML node, with each entry's comment above its key
+func yamlNode(value interface{}) (node *yaml.Node) {
+	switch v := value.(type) {
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	case configNumber:
+		tag := "!!int"
+		if strings.ContainsAny(string(v), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: string(v)}
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v)}
+	case []interface{}:
+		node = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range v {
+			node.Content = append(node.Content, yamlNode(item))
+		}
+		return
+	case []configEntry:
+		node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, entry := range v {
+			key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: entry.key, HeadComment: entry.comment}
+			node.Content = append(node.Content, key, yamlNode(entry.value))
+		}
+		return
+	default:
+		node = &yaml.Node{}
+		node.Encode(v)
+		return
+	}
+}
+
+// tomlBareKey Keys that can be written without quotes
+var tomlBareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// tomlKeyName Key as written in TOML, quoted unless it is a bare key
+func tomlKeyName(key string) string {
+	if tomlBareKey.MatchString(key) {
+		return key
+	}
+	return jsonString(key)
+}
+
+// writeTOML Write entries of the table at path, values first then nested tables, as TOML requires
+// - Arrays of elements are written as arrays of tables, nil values are left out
+func writeTOML(sb *strings.Builder, path string, entries []configEntry) {
+	var tablePath string
+
+	for _, entry := range entries {
+		if entry.value == nil || tomlTable(entry.value) {
+			continue
+		}
+		if entry.comment != "" {
+			sb.WriteString("# " + entry.comment + "\n")
+		}
+		sb.WriteString(tomlKeyName(entry.key) + " = " + tomlValue(entry.value) + "\n")
+	}
+
+	for _, entry := range entries {
+		if entry.value == nil || !tomlTable(entry.value) {
+			continue
+		}
+		tablePath = tomlKeyName(entry.key)
+		if path != "" {
+			tablePath = path + "." + tablePath
+		}
+		sb.WriteString("\n")
+		if entry.comment != "" {
+			sb.WriteString("# " + entry.comment + "\n")
+		}
+		if table, ok := entry.value.([]configEntry); ok {
+			sb.WriteString("[" + tablePath + "]\n")
+			writeTOML(sb, tablePath, table)
+			continue
+		}
+		for i, item := range entry.value.([]interface{}) {
+			if i > 0 {
+				sb.WriteString("\n")
+			}
+			sb.WriteString("[[" + tablePath + "]]\n")
+			writeTOML(sb, tablePath, item.([]configEntry))
+		}
+	}
+}
+
+// tomlTable Is value written as a table, a nested element or non-empty array of elements
+func tomlTable(value interface{}) bool {
+	switch v := value.(type) {
+	case []configEntry:
+		return true
+	case []interface{}:
+		if len(v) == 0 {
+			return false
+		}
+		for _, item := range v {
+			if _, ok := item.([]configEntry); !ok {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// tomlValue Format value inline, nested elements as inline tables
+func tomlValue(value interface{}) string {
+	var items []string
+
+	switch v := value.(type) {
+	case configNumber:
+		return string(v)
+	case string:
+		return jsonString(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		for _, item := range v {
+			items = append(items, tomlValue(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case []configEntry:
+		for _, entry := range v {
+			if entry.value != nil {
+				items = append(items, tomlKeyName(entry.key)+" = "+tomlValue(entry.value))
+			}
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	default:
+		return jsonString(fmt.Sprintf("%v", v))
+	}
+}
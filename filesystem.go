@@ -15,6 +15,7 @@ type FileDetail struct {
 }
 
 // DistinctFilenames Create Parsed{} struct for each filename, validating and formatting names
+// - FileDetails are returned in the order filenames are given
 func DistinctFilenames(filenames []string, errList *ErrList) (fileDetails []FileDetail) {
 	var (
 		err                           error
@@ -28,8 +29,9 @@ func DistinctFilenames(filenames []string, errList *ErrList) (fileDetails []File
 	// Map to determine distinct names
 	usednames := make(map[string][]string)
 
-	// Map to make sure full-path names are unique
+	// Map to make sure full-path names are unique, and list to keep them in the order given
 	fullnameMap := make(map[string]FileDetail)
+	var fullpaths []string
 
 	for _, filename = range filenames {
 
@@ -69,6 +71,7 @@ func DistinctFilenames(filenames []string, errList *ErrList) (fileDetails []File
 			}
 		}
 		fullnameMap[fullpath] = file
+		fullpaths = append(fullpaths, fullpath)
 
 		// List base names used, to see if names are distinct
 		items, ok = usednames[file.DistinctName]
@@ -131,9 +134,9 @@ func DistinctFilenames(filenames []string, errList *ErrList) (fileDetails []File
 		}
 	}
 
-	// Copy results from fullnameMap
-	for _, file = range fullnameMap {
-		fileDetails = append(fileDetails, file)
+	// Copy results from fullnameMap, in the order given
+	for _, fullpath = range fullpaths {
+		fileDetails = append(fileDetails, fullnameMap[fullpath])
 	}
 	return
 }
@@ -1,10 +1,15 @@
 module github.com/AndrewDonelson/goutils
 
-go 1.13
+go 1.18
 
 require (
 	github.com/AndrewDonelson/golog v0.0.0-20191110210651-c1545b675554
-	github.com/boltdb/bolt v1.3.1
+	github.com/BurntSushi/toml v0.3.1
+	github.com/fsnotify/fsnotify v1.7.0
 	github.com/golangci/golangci-lint v1.21.0 // indirect
+	go.etcd.io/bbolt v1.3.9
+	gopkg.in/yaml.v3 v3.0.1
 	gotest.tools v2.2.0+incompatible
 )
+
+require golang.org/x/sys v0.4.0 // indirect
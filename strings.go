@@ -39,3 +39,34 @@ func ValidateEmailAddress(email string) (err error) {
 	}
 	return
 }
+
+// StringEditDistance helper function to return the Levenshtein distance between two strings
+// - The number of single character insertions, deletions or substitutions to change a into b
+func StringEditDistance(a, b string) int {
+	var i, j, cost int
+
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j = range prev {
+		prev[j] = j
+	}
+	for i = 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j = 1; j <= len(rb); j++ {
+			cost = 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j] + 1
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+			if prev[j-1]+cost < curr[j] {
+				curr[j] = prev[j-1] + cost
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}